@@ -0,0 +1,254 @@
+package agollo
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sixgoatsh/agollo/core/backup"
+	"github.com/sixgoatsh/agollo/core/client/balancer"
+	"github.com/sixgoatsh/agollo/core/config"
+	"github.com/sixgoatsh/agollo/core/options"
+)
+
+type adminError struct {
+	Time            time.Time             `json:"time"`
+	ConfigServerURL string                `json:"configServerUrl,omitempty"`
+	Namespace       string                `json:"namespace,omitempty"`
+	Notifications   []config.Notification `json:"notifications,omitempty"`
+	Error           string                `json:"error"`
+}
+
+type adminNamespace struct {
+	Namespace      string                `json:"namespace"`
+	ReleaseKey     string                `json:"releaseKey"`
+	NotificationID int                   `json:"notificationId"`
+	Restored       bool                  `json:"restored"` // 使用备份恢复的release key，还未从apollo获取过配置
+	Configurations config.Configurations `json:"configurations"`
+}
+
+type adminBackup struct {
+	Namespace      string    `json:"namespace"`
+	Exists         bool      `json:"exists"`
+	ReleaseKey     string    `json:"releaseKey,omitempty"`
+	NotificationID int       `json:"notificationId,omitempty"`
+	FetchedAt      time.Time `json:"fetchedAt,omitempty"`
+	Expired        bool      `json:"expired"`
+	UpToDate       bool      `json:"upToDate"` // 备份的release key与当前缓存的一致
+	Error          string    `json:"error,omitempty"`
+}
+
+type adminBalancer struct {
+	LastSelected string                    `json:"lastSelected,omitempty"`
+	Endpoints    []balancer.EndpointStatus `json:"endpoints,omitempty"` // 仅HealthBalancer提供
+}
+
+type adminReload struct {
+	Namespace string `json:"namespace"`
+	Status    int    `json:"status"`
+	Error     string `json:"error,omitempty"`
+}
+
+// AdminHandler 返回用于排查问题的http.Handler，可以挂载到已有的debug mux上，例如：
+//
+//	mux.Handle("/debug/agollo/", agollo.AdminHandler())
+//
+// 按照路径的最后一段路由，未知的路径返回404：
+//
+//	GET  /           appID、cluster及已初始化的namespace
+//	GET  /namespaces 缓存的配置、release key及notificationID
+//	GET  /balancer   负载均衡最近选择的ConfigServer及各ConfigServer的健康状态
+//	GET  /errors     最近的轮训错误
+//	GET  /backup     各namespace的备份状态
+//	GET  /ready      RequiredNamespaces就绪时返回200，否则返回503
+//	POST /reload?namespace=xxx 忽略release key，从非缓存接口强制重新下载namespace
+//	POST /resync     从非缓存接口重新加载所有namespace
+//
+// 注意：/namespaces会输出完整的、已解密的配置，其中可能包含密码等敏感信息，
+// 且reload、resync会向apollo发送请求，不能在没有鉴权的情况下对外暴露
+func (a *goApollo) AdminHandler() http.Handler {
+	return http.HandlerFunc(a.serveAdmin)
+}
+
+func (a *goApollo) serveAdmin(w http.ResponseWriter, r *http.Request) {
+	route := path.Base(path.Clean("/" + r.URL.Path))
+	if r.URL.Path == "" || strings.HasSuffix(r.URL.Path, "/") {
+		// 挂载的根路径，例如/debug/agollo/
+		route = ""
+	}
+
+	var handler func(r *http.Request) (int, interface{})
+	method := http.MethodGet
+	switch route {
+	case "":
+		handler = a.adminIndex
+	case "namespaces":
+		handler = a.adminNamespaces
+	case "balancer":
+		handler = a.adminBalancer
+	case "errors":
+		handler = a.adminErrors
+	case "backup":
+		handler = a.adminBackup
+	case "ready":
+		handler = a.adminReady
+	case "reload":
+		handler, method = a.adminReload, http.MethodPost
+	case "resync":
+		handler, method = a.adminResync, http.MethodPost
+	default:
+		writeAdminJSON(w, http.StatusNotFound, map[string]string{"error": "not found: " + r.URL.Path})
+		return
+	}
+
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		writeAdminJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	status, v := handler(r)
+	writeAdminJSON(w, status, v)
+}
+
+func (a *goApollo) adminIndex(*http.Request) (int, interface{}) {
+	return http.StatusOK, map[string]interface{}{
+		"appId":      a.opts.Conf.AppID,
+		"cluster":    a.opts.Conf.ClusterName,
+		"namespaces": a.initializedNamespaces(),
+	}
+}
+
+func (a *goApollo) adminNamespaces(*http.Request) (int, interface{}) {
+	views := []adminNamespace{}
+	for _, namespace := range a.initializedNamespaces() {
+		releaseKey, _ := a.releaseKeyMap.Load(namespace)
+		notificationID, ok := a.notificationMap.Load(namespace)
+		if !ok {
+			notificationID = defaultNotificationID
+		}
+		_, restored := a.restored.Load(namespace)
+
+		view := adminNamespace{
+			Namespace:      namespace,
+			Restored:       restored,
+			Configurations: a.getNameSpace(namespace),
+		}
+		view.ReleaseKey, _ = releaseKey.(string)
+		view.NotificationID, _ = notificationID.(int)
+		views = append(views, view)
+	}
+	return http.StatusOK, views
+}
+
+func (a *goApollo) adminBalancer(*http.Request) (int, interface{}) {
+	var view adminBalancer
+	view.LastSelected, _ = a.lastServer.Load().(string)
+	if hb, ok := a.balance.(balancer.HealthBalancer); ok {
+		view.Endpoints = hb.Endpoints()
+	}
+	return http.StatusOK, view
+}
+
+func (a *goApollo) adminErrors(*http.Request) (int, interface{}) {
+	a.errorsLock.Lock()
+	defer a.errorsLock.Unlock()
+	return http.StatusOK, append([]adminError{}, a.recentErrors...)
+}
+
+func (a *goApollo) adminBackup(*http.Request) (int, interface{}) {
+	views := []adminBackup{}
+	for _, namespace := range a.initializedNamespaces() {
+		view := adminBackup{Namespace: namespace}
+		entry, err := a.opts.BackupStore.Load(namespace)
+		switch err {
+		case nil:
+			releaseKey, _ := a.releaseKeyMap.Load(namespace)
+			view.Exists = true
+			view.ReleaseKey = entry.ReleaseKey
+			view.NotificationID = entry.NotificationID
+			view.FetchedAt = entry.FetchedAt
+			view.Expired = entry.Expired(a.opts.MaxBackupAge, time.Now())
+			view.UpToDate = entry.ReleaseKey != "" && entry.ReleaseKey == releaseKey
+		case backup.ErrNotFound:
+		default:
+			view.Error = err.Error()
+		}
+		views = append(views, view)
+	}
+	return http.StatusOK, views
+}
+
+func (a *goApollo) adminReady(*http.Request) (int, interface{}) {
+	if err := a.Ready(); err != nil {
+		return http.StatusServiceUnavailable, map[string]interface{}{"ready": false, "error": err.Error()}
+	}
+	return http.StatusOK, map[string]interface{}{"ready": true}
+}
+
+func (a *goApollo) adminReload(r *http.Request) (int, interface{}) {
+	namespace := r.URL.Query().Get("namespace")
+	if _, ok := a.initialized.Load(namespace); !ok {
+		return http.StatusNotFound, map[string]string{"error": "namespace not initialized: " + namespace}
+	}
+
+	status, err := a.refreshNamespace(r.Context(), namespace, options.FetchNonCache, true)
+	view := adminReload{Namespace: namespace, Status: status}
+	if err != nil {
+		view.Error = err.Error()
+		return http.StatusBadGateway, view
+	}
+	return http.StatusOK, view
+}
+
+func (a *goApollo) adminResync(r *http.Request) (int, interface{}) {
+	a.refresh(r.Context(), func(string) options.FetchStrategy {
+		return options.FetchNonCache
+	})
+	return http.StatusOK, a.RefreshStats()
+}
+
+// initializedNamespaces 按名称排序的已初始化namespace
+func (a *goApollo) initializedNamespaces() []string {
+	namespaces := []string{}
+	a.initialized.Range(func(key, _ interface{}) bool {
+		namespaces = append(namespaces, key.(string))
+		return true
+	})
+	sort.Strings(namespaces)
+	return namespaces
+}
+
+// selected 记录负载均衡选择的ConfigServer
+func (a *goApollo) selected(server string) {
+	a.lastServer.Store(server)
+	a.opts.Metrics.BalancerSelect(server)
+}
+
+// recordError 保留最近maxRecentErrors条轮训错误
+func (a *goApollo) recordError(e *LongPollerError) {
+	view := adminError{
+		Time:            time.Now(),
+		ConfigServerURL: e.ConfigServerURL,
+		Namespace:       e.Namespace,
+		Notifications:   e.Notifications,
+	}
+	if e.Err != nil {
+		view.Error = e.Err.Error()
+	}
+
+	a.errorsLock.Lock()
+	defer a.errorsLock.Unlock()
+	a.recentErrors = append(a.recentErrors, view)
+	if len(a.recentErrors) > maxRecentErrors {
+		a.recentErrors = a.recentErrors[len(a.recentErrors)-maxRecentErrors:]
+	}
+}
+
+func writeAdminJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
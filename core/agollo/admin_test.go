@@ -0,0 +1,109 @@
+package agollo
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sixgoatsh/agollo/core/apollotest"
+	"github.com/sixgoatsh/agollo/core/backup"
+	"github.com/sixgoatsh/agollo/core/client"
+	"github.com/sixgoatsh/agollo/core/client/balancer"
+	"github.com/sixgoatsh/agollo/core/config"
+	"github.com/sixgoatsh/agollo/core/options"
+)
+
+func TestAdminHandler(t *testing.T) {
+	appid := "test"
+	server := apollotest.NewServer(apollotest.Hold(500 * time.Millisecond))
+	defer server.Close()
+	releaseKey := server.Publish(appid, "default", "application", config.Configurations{"timeout": "100"})
+
+	a, err := NewGoApollo(server.URL, appid,
+		client.New(),
+		balancer.NewHealthBalancer([]string{server.URL}),
+		options.PreloadNamespaces("application"),
+		options.WithBackupStore(backup.NewMemoryStore()),
+		options.LongPollerInterval(10*time.Millisecond),
+	)
+	assert.Nil(t, err)
+
+	mux := http.NewServeMux()
+	mux.Handle("/debug/agollo/", a.AdminHandler())
+	serve := func(method, target string, out interface{}) int {
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, httptest.NewRequest(method, target, nil))
+		if out != nil {
+			assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), out))
+		}
+		return recorder.Code
+	}
+
+	var namespaces []adminNamespace
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/debug/agollo/namespaces", &namespaces))
+	assert.Equal(t, []adminNamespace{{
+		Namespace:      "application",
+		ReleaseKey:     releaseKey,
+		NotificationID: 1,
+		Configurations: config.Configurations{"timeout": "100"},
+	}}, namespaces)
+
+	var backups []adminBackup
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/debug/agollo/backup", &backups))
+	assert.Len(t, backups, 1)
+	assert.True(t, backups[0].Exists)
+	assert.True(t, backups[0].UpToDate)
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/debug/agollo/ready", nil))
+
+	var lb adminBalancer
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/debug/agollo/balancer", &lb))
+	assert.Equal(t, server.URL, lb.LastSelected)
+	assert.Len(t, lb.Endpoints, 1)
+
+	// 未启动长轮训时通过reload获取最新的配置
+	releaseKey = server.Publish(appid, "default", "application", config.Configurations{"timeout": "200"})
+	assert.Equal(t, http.StatusMethodNotAllowed, serve(http.MethodGet, "/debug/agollo/reload?namespace=application", nil))
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodPost, "/debug/agollo/reload?namespace=unknown", nil))
+	var reload adminReload
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/debug/agollo/reload?namespace=application", &reload))
+	assert.Equal(t, http.StatusOK, reload.Status)
+	assert.Equal(t, "200", a.Get("timeout"))
+
+	// 配置未变化时同样重新下载，而不是返回304
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/debug/agollo/reload?namespace=application", &reload))
+	assert.Equal(t, http.StatusOK, reload.Status)
+	assert.Equal(t, "200", a.Get("timeout"))
+
+	// 强制重新加载失败时保留原来的release key
+	server.Inject(apollotest.Fault{Path: "/configs/", Status: http.StatusInternalServerError, Times: 1})
+	assert.Equal(t, http.StatusBadGateway, serve(http.MethodPost, "/debug/agollo/reload?namespace=application", &reload))
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/debug/agollo/namespaces", &namespaces))
+	assert.Equal(t, releaseKey, namespaces[0].ReleaseKey)
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/debug/agollo/backup", &backups))
+	assert.True(t, backups[0].UpToDate)
+
+	var index map[string]interface{}
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/debug/agollo/", &index))
+	assert.Equal(t, appid, index["appId"])
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/debug/agollo/unknown", nil))
+
+	var stats RefreshStats
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/debug/agollo/resync", &stats))
+	assert.Equal(t, uint64(1), stats.Runs)
+
+	// 即使没有消费errorsCh也保留最近的轮训错误
+	server.Inject(apollotest.Fault{Path: "/notifications/v2", Status: http.StatusInternalServerError})
+	a.Start()
+	defer a.Stop()
+
+	assert.Eventually(t, func() bool {
+		var errs []adminError
+		serve(http.MethodGet, "/debug/agollo/errors", &errs)
+		return len(errs) > 0 && errs[0].Error != ""
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -1,19 +1,25 @@
 package agollo
 
 import (
+	"context"
 	"encoding/json"
-	"io/ioutil"
+	"errors"
+	"fmt"
 	"net/http"
 	"os"
 	"path"
-	"path/filepath"
+	"reflect"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/sixgoatsh/agollo/core/backup"
 	"github.com/sixgoatsh/agollo/core/client"
 	"github.com/sixgoatsh/agollo/core/client/balancer"
 	"github.com/sixgoatsh/agollo/core/config"
 	"github.com/sixgoatsh/agollo/core/options"
+	"github.com/sixgoatsh/agollo/pkg/backoff"
+	"github.com/sixgoatsh/agollo/pkg/log"
 	"github.com/sixgoatsh/agollo/pkg/util/str"
 )
 
@@ -23,15 +29,48 @@ var (
 
 type GoApollo interface {
 	Start() <-chan *LongPollerError
+	StartCtx(ctx context.Context) <-chan *LongPollerError
 	Stop()
 	Get(key string, opts ...options.GetOption) string
+	GetInt(key string, opts ...options.GetOption) int
+	GetIntE(key string, opts ...options.GetOption) (int, error)
+	GetInt64(key string, opts ...options.GetOption) int64
+	GetInt64E(key string, opts ...options.GetOption) (int64, error)
+	GetBool(key string, opts ...options.GetOption) bool
+	GetBoolE(key string, opts ...options.GetOption) (bool, error)
+	GetFloat64(key string, opts ...options.GetOption) float64
+	GetFloat64E(key string, opts ...options.GetOption) (float64, error)
+	GetDuration(key string, opts ...options.GetOption) time.Duration
+	GetDurationE(key string, opts ...options.GetOption) (time.Duration, error)
+	GetTime(key string, opts ...options.GetOption) time.Time
+	GetTimeE(key string, opts ...options.GetOption) (time.Time, error)
+	GetStringSlice(key string, opts ...options.GetOption) []string
+	GetStringSliceE(key string, opts ...options.GetOption) ([]string, error)
+	GetStringMap(key string, opts ...options.GetOption) map[string]interface{}
+	GetStringMapE(key string, opts ...options.GetOption) (map[string]interface{}, error)
 	GetNameSpace(namespace string) config.Configurations
+	Unmarshal(namespace string, out interface{}) error
+	Bind(namespace string, ptr interface{}) (*Binding, error)
 	Watch() <-chan *ApolloResponse
 	WatchNamespace(namespace string, stop chan bool) <-chan *ApolloResponse
+	AddChangeListener(namespace string, fn func(*ApolloResponse), opts ...options.ListenerOption) (unsubscribe func())
+	AddKeyListener(namespace, keyPrefix string, fn func(*ApolloResponse), opts ...options.ListenerOption) (unsubscribe func())
 	Options() options.Options
+	BackoffState() backoff.State
+	GetContent(namespace string) string
+	GetTree(namespace string) (map[string]interface{}, error)
+	Chain(namespaces ...string) (*NamespaceChain, error)
+	Overrides() []Override
+	Subscribe(appID, cluster string, namespaces ...string) (GoApollo, error)
+	RefreshStats() RefreshStats
+	AdminHandler() http.Handler
+	Ready() error
+	WaitReady(ctx context.Context, namespaces ...string) error
 }
 
 type ApolloResponse struct {
+	AppID     string
+	Cluster   string
 	Namespace string
 	OldValue  config.Configurations
 	NewValue  config.Configurations
@@ -56,16 +95,50 @@ type goApollo struct {
 	releaseKeyMap   sync.Map // key: namespace value: releaseKey
 	cache           sync.Map // key: namespace value: Configurations
 	initialized     sync.Map // key: namespace value: bool
+	restored        sync.Map // key: namespace value: *backup.Entry 启动时从备份恢复，apollo返回304时使用
+	contents        sync.Map // key: namespace value: string json、yaml格式namespace解析前的原始内容
+	loaded          sync.Map // key: namespace value: string 从apollo或者备份加载成功时的来源
+	loadErrors      sync.Map // key: namespace value: error 最近一次加载失败的原因
 
 	watchCh             chan *ApolloResponse // watch all namespace
-	watchNamespaceChMap sync.Map             // key: namespace value: chan *ApolloResponse
+	watchChLock         sync.Mutex
+	watchNamespaceChMap sync.Map // key: namespace value: chan *ApolloResponse
+
+	bindings     map[string][]*Binding // key: namespace value: 绑定的结构体
+	bindingsLock sync.RWMutex
+
+	listeners     []*listener
+	listenersLock sync.RWMutex
 
 	errorsCh chan *LongPollerError
 
+	longPollBackoff *backoff.Backoff
+
+	// 长轮训与定期刷新互斥地重新加载namespace，防止重复发送Watch事件
+	reloadLock sync.Mutex
+
+	refreshStats     RefreshStats
+	refreshStatsLock sync.Mutex
+
+	envOverrides []Override // 创建时从环境变量读取的覆盖配置
+
+	lastServer   atomic.Value // 负载均衡最近一次选择的ConfigServer
+	recentErrors []adminError // 最近的轮训错误，即使使用者不消费errorsCh也能通过AdminHandler查看
+	errorsLock   sync.Mutex
+
+	parent   *goApollo            // Subscribe创建的goApollo指向创建它的goApollo
+	subs     map[string]*goApollo // key: appID+cluster Subscribe订阅的其他appID及cluster
+	subsLock sync.Mutex
+	startCtx context.Context // Start之后订阅的appID使用该ctx启动长轮训
+
 	runOnce  sync.Once
 	stop     bool
 	stopCh   chan struct{}
 	stopLock sync.Mutex
+
+	// Stop时取消，中断所有正在进行的请求，包括被hold住的长轮训请求
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
 func NewWithConfigFile(configFilePath string, opts ...options.Option) (GoApollo, error) {
@@ -103,11 +176,18 @@ func NewWithConfigFile(configFilePath string, opts ...options.Option) (GoApollo,
 }
 
 func NewGoApollo(configServerURL, appID string, apolloC client.IApolloClient, ba balancer.Balancer, opts ...options.Option) (GoApollo, error) {
+	return NewGoApolloCtx(context.Background(), configServerURL, appID, apolloC, ba, opts...)
+}
+
+// NewGoApolloCtx ctx仅用于控制初始化预加载namespace的时间，超时后返回的GoApollo仍然可用
+func NewGoApolloCtx(ctx context.Context, configServerURL, appID string, apolloC client.IApolloClient, ba balancer.Balancer, opts ...options.Option) (GoApollo, error) {
 	a := &goApollo{
 		stopCh:       make(chan struct{}),
 		errorsCh:     make(chan *LongPollerError),
 		apolloClient: apolloC,
 		balance:      ba,
+		bindings:     map[string][]*Binding{},
+		subs:         map[string]*goApollo{},
 	}
 	var err error
 	a.opts, err = options.NewOptions(configServerURL, appID, opts...)
@@ -115,37 +195,80 @@ func NewGoApollo(configServerURL, appID string, apolloC client.IApolloClient, ba
 		return nil, err
 	}
 
-	return a, a.initNamespace(a.opts.PreloadNamespaces...)
+	// 未传入时根据Options创建默认的客户端及负载均衡
+	if a.apolloClient == nil && a.opts.LocalDir != "" {
+		a.apolloClient = client.NewLocalClient(a.opts.LocalDir)
+		if a.balance == nil {
+			a.balance = balancer.NewRoundRobin([]string{"file://" + a.opts.LocalDir})
+		}
+	}
+	if a.apolloClient == nil {
+		a.apolloClient = client.NewWithRestClient(a.opts.RestClient)
+	}
+	if a.balance == nil {
+		a.balance, err = balancer.NewBalancer(a.opts.Conf, a.opts.EnableSLB, a.opts.RefreshIntervalInSecond, a.opts.Logger, a.apolloClient)
+		if err != nil {
+			return nil, err
+		}
+	}
+	a.ctx, a.cancel = context.WithCancel(context.Background())
+	a.envOverrides = envOverrides(os.Environ(), a.opts.EnvOverridePrefix, a.opts.EnvOverrideKeyMapper)
+	a.longPollBackoff = backoff.New(a.opts.RetryPolicy)
+
+	err = a.initNamespace(ctx, a.opts.PreloadNamespaces...)
+	for _, sub := range a.opts.Subscriptions {
+		if _, subErr := a.subscribe(ctx, sub.AppID, sub.Cluster, sub.Namespaces...); err == nil {
+			err = subErr
+		}
+	}
+	// 必须的namespace未就绪时返回所有失败的namespace，同时保留初始化的错误
+	if readyErr := a.Ready(); readyErr != nil {
+		notReady := readyErr.(*NotReadyError)
+		notReady.Init = err
+		err = notReady
+	}
+	return a, err
 }
 
-func (a *goApollo) initNamespace(namespaces ...string) error {
-	var errs []error
+func (a *goApollo) initNamespace(ctx context.Context, namespaces ...string) error {
+	var errs []*NamespaceError
 	for _, namespace := range namespaces {
 		_, found := a.initialized.LoadOrStore(namespace, true)
 		if !found {
-			// (1)读取配置 (2)设置初始化notificationMap
-			status, _, err := a.reloadNamespace(a.balance, a.apolloClient, namespace)
-
-			// 这里没法光凭靠error==nil来判断namespace是否存在，即使http请求失败，如果开启 容错，会导致error丢失
-			// 从而可能将一个不存在的namespace拿去调用getRemoteNotifications导致被hold
-			a.setNotificationIDFromRemote(namespace, status == http.StatusOK)
+			// (1)从备份恢复release key (2)读取配置 (3)设置初始化notificationMap
+			entry := a.restoreBackup(namespace)
+			status, _, err := a.reloadNamespace(ctx, a.balance, a.apolloClient, namespace)
+
+			if status == http.StatusNotModified && entry != nil && entry.NotificationID > defaultNotificationID {
+				// 备份的配置仍是最新的，直接使用备份的notificationID，
+				// 即使比apollo中的旧，第一次长轮训也会立即返回并重新加载
+				a.notificationMap.Store(namespace, entry.NotificationID)
+			} else {
+				// 这里没法光凭靠error==nil来判断namespace是否存在，即使http请求失败，如果开启 容错，会导致error丢失
+				// 从而可能将一个不存在的namespace拿去调用getRemoteNotifications导致被hold
+				a.setNotificationIDFromRemote(ctx, namespace, status == http.StatusOK || status == http.StatusNotModified)
+				if status == http.StatusOK && err == nil {
+					// 补充备份中的notificationID
+					a.logBackupError(namespace, a.backup(namespace))
+				}
+			}
 
 			// 即使存在异常也需要继续初始化下去，有一些使用者会拂掠初始化时的错误
 			// 期望在未来某个时间点apollo的服务器恢复过来
 			if err != nil {
-				errs = append(errs, err)
+				errs = append(errs, newNamespaceError(namespace, "", status, err))
 			}
 		}
 	}
 
 	if len(errs) > 0 {
-		return errs[0]
+		return &InitError{Errors: errs}
 	}
 
 	return nil
 }
 
-func (a *goApollo) setNotificationIDFromRemote(namespace string, exists bool) {
+func (a *goApollo) setNotificationIDFromRemote(ctx context.Context, namespace string, exists bool) {
 	if !exists {
 		// 不能正常获取notificationID的设置为默认notificationID
 		// 为之后longPoll提供localNoticationID参数
@@ -162,7 +285,7 @@ func (a *goApollo) setNotificationIDFromRemote(namespace string, exists bool) {
 	// 由于apollo去getRemoteNotifications获取一个不存在的namespace的notificationID时会hold请求90秒
 	// (1) 为防止意外传入一个不存在的namespace而发生上述情况，仅将成功获取配置在apollo存在的namespace,去初始化notificationID
 	// (2) 此处忽略error返回，在容灾逻辑下配置能正确读取而去获取notificationid可能会返回http请求失败，防止服务不能正常容灾启动
-	remoteNotifications, _ := a.getRemoteNotifications(localNotifications)
+	_, remoteNotifications, _ := a.getRemoteNotifications(ctx, localNotifications)
 	if len(remoteNotifications) > 0 {
 		for _, notification := range remoteNotifications {
 			// 设置namespace初始化的notificationID
@@ -174,57 +297,109 @@ func (a *goApollo) setNotificationIDFromRemote(namespace string, exists bool) {
 	}
 }
 
-func (a *goApollo) reloadNamespace(balance balancer.Balancer, nonCacheClient client.IApolloClient, namespace string) (status int, conf config.Configurations, err error) {
+func (a *goApollo) reloadNamespace(ctx context.Context, balance balancer.Balancer, nonCacheClient client.IApolloClient, namespace string) (status int, conf config.Configurations, err error) {
+	return a.loadNamespace(ctx, balance, nonCacheClient, namespace, a.opts.NamespaceFetchStrategy(namespace))
+}
+
+// loadNamespace 按照strategy指定的接口加载namespace，两种接口的结果统一为非缓存接口的HTTP Status
+func (a *goApollo) loadNamespace(ctx context.Context, balance balancer.Balancer, apolloClient client.IApolloClient, namespace string, strategy options.FetchStrategy) (status int, conf config.Configurations, err error) {
 	clientConf := a.opts.Conf
+	defer func() {
+		if err != nil {
+			err = newNamespaceError(namespace, clientConf.ConfigServerUrl, status, err)
+		}
+	}()
+
 	clientConf.ConfigServerUrl, err = balance.Select()
 	clientConf.NamespaceName = namespace
 	if err != nil {
-		a.log("Action", "BalancerSelect", "Error", err)
+		a.logger().Error("select config server failed", "namespace", namespace, "error", err)
+		a.markFailed(namespace, "", status, err)
 		return
 	}
+	a.selected(clientConf.ConfigServerUrl)
 
 	var (
 		serverConf          *client.NonCacheResp
 		cachedReleaseKey, _ = a.releaseKeyMap.LoadOrStore(namespace, "")
 	)
 
-	status, serverConf, err = nonCacheClient.GetConfigsFromNonCache(
-		clientConf,
-		client.ReleaseKey(cachedReleaseKey.(string)),
-	)
+	start := time.Now()
+	if strategy == options.FetchCache {
+		status, serverConf, err = a.getConfigsFromCache(ctx, apolloClient, clientConf, cachedReleaseKey.(string))
+	} else {
+		status, serverConf, err = apolloClient.GetConfigsFromNonCacheCtx(
+			ctx,
+			clientConf,
+			client.ReleaseKey(cachedReleaseKey.(string)),
+		)
+	}
+	report(ctx, balance, clientConf.ConfigServerUrl, status, err)
+	a.opts.Metrics.Fetch(a.opts.Conf.AppID, a.opts.Conf.ClusterName, namespace, strategy, status, err, time.Since(start))
 	if err != nil {
-		a.log("ConfigServerUrl", clientConf.ConfigServerUrl, "Namespace", namespace,
-			"Action", "GetConfigsFromNonCache", "ServerResponseStatus", status,
-			"Error", err)
+		a.logger().Warn("fetch namespace failed", "config_server", clientConf.ConfigServerUrl, "namespace", namespace,
+			"strategy", strategy, "status", status, "error", err)
 	}
 
 	switch status {
 	case http.StatusOK: // 正常响应
-		a.cache.Store(namespace, serverConf.Configurations)     // 覆盖旧缓存
+		// 解析失败时保留旧缓存，也不更新release_key，下次加载时重新解析
+		if conf, err = a.storeConfigurations(namespace, serverConf.Configurations); err != nil {
+			a.logger().Error("parse namespace content failed", "namespace", namespace, "error", err)
+			a.markFailed(namespace, clientConf.ConfigServerUrl, status, err)
+			return
+		}
+		a.markLoaded(namespace, loadedFromServer)
 		a.releaseKeyMap.Store(namespace, serverConf.ReleaseKey) // 存储最新的release_key
-		conf = serverConf.Configurations
+		a.restored.Delete(namespace)
+		a.opts.Metrics.Synced(a.opts.Conf.AppID, a.opts.Conf.ClusterName, namespace, time.Now())
+		a.logger().Info("namespace loaded", "namespace", namespace, "release_key", serverConf.ReleaseKey)
 
 		// 备份配置
-		if err = a.backup(); err != nil {
-			a.log("BackupFile", a.opts.BackupFile, "Namespace", namespace,
-				"Action", "Backup", "Error", err)
+		if err = a.backup(namespace); err != nil {
+			a.logBackupError(namespace, err)
 			return
 		}
 	case http.StatusNotModified: // 服务端未修改配置情况下返回304
+		if entry, ok := a.restored.Load(namespace); ok {
+			// release key来自备份，配置同样使用备份
+			a.restored.Delete(namespace)
+			if _, err = a.storeConfigurations(namespace, entry.(*backup.Entry).Configurations); err != nil {
+				a.logger().Error("parse namespace content failed", "namespace", namespace, "error", err)
+				a.markFailed(namespace, clientConf.ConfigServerUrl, status, err)
+				return
+			}
+		}
+		a.markLoaded(namespace, loadedFromServer)
 		conf = a.getNameSpace(namespace)
+		a.opts.Metrics.Synced(a.opts.Conf.AppID, a.opts.Conf.ClusterName, namespace, time.Now())
+
+		// 刷新备份的同步时间，MaxBackupAge从最近一次同步成功开始计算
+		a.logBackupError(namespace, a.touchBackup(namespace))
 	default:
 		conf = config.Configurations{}
+		if err == nil {
+			// 非缓存接口非200时没有返回error，404等同样作为失败返回
+			err = &client.StatusError{StatusCode: status}
+		}
+		a.markFailed(namespace, clientConf.ConfigServerUrl, status, err)
 
 		// 异常状况下，如果开启容灾，则读取备份
 		if a.opts.FailTolerantOnBackupExists {
 			backupConfig, err := a.loadBackup(namespace)
+			a.opts.Metrics.Backup(a.opts.Conf.AppID, a.opts.Conf.ClusterName, namespace, options.BackupFallback, err)
 			if err != nil {
-				a.log("BackupFile", a.opts.BackupFile, "Namespace", namespace,
-					"Action", "LoadBackup", "Error", err)
+				a.logger().Error("load backup failed", "namespace", namespace, "error", err)
 				return status, nil, err
 			}
 
-			a.cache.Store(namespace, backupConfig)
+			backupConfig, err = a.storeConfigurations(namespace, backupConfig)
+			if err != nil {
+				a.logger().Error("parse namespace content failed", "namespace", namespace, "error", err)
+				a.markFailed(namespace, clientConf.ConfigServerUrl, status, err)
+				return status, nil, err
+			}
+			a.markLoaded(namespace, loadedFromBackup)
 			return status, backupConfig, nil
 		}
 	}
@@ -233,28 +408,29 @@ func (a *goApollo) reloadNamespace(balance balancer.Balancer, nonCacheClient cli
 }
 
 func (a *goApollo) Get(key string, opts ...options.GetOption) string {
-	getOpts := a.opts.NewGetOptions(opts...)
-
-	val, found := a.GetNameSpace(getOpts.Namespace)[key]
-	if !found {
-		return getOpts.DefaultValue
-	}
+	val, _ := a.lookup(key, opts)
 
 	v, _ := str.ToStringE(val)
 	return v
 }
 
+// GetNameSpace 开启Interpolate时返回解析占位符后的配置
 func (a *goApollo) GetNameSpace(namespace string) config.Configurations {
+	return a.interpolate(namespace, a.loadNameSpace(namespace))
+}
+
+// loadNameSpace 返回叠加覆盖配置、未解析占位符的配置，开启AutoFetchOnCacheMiss时获取非预加载的namespace
+func (a *goApollo) loadNameSpace(namespace string) config.Configurations {
 	conf, found := a.cache.LoadOrStore(namespace, config.Configurations{})
 	if !found && a.opts.AutoFetchOnCacheMiss {
-		err := a.initNamespace(namespace)
+		err := a.initNamespace(a.ctx, namespace)
 		if err != nil {
-			a.log("Action", "InitNamespace", "Error", err)
+			a.logger().Warn("init namespace failed", "namespace", namespace, "error", err)
 		}
-		return a.getNameSpace(namespace)
+		return a.effective(namespace)
 	}
 
-	return conf.(config.Configurations)
+	return a.applyOverrides(namespace, conf.(config.Configurations))
 }
 
 func (a *goApollo) getNameSpace(namespace string) config.Configurations {
@@ -271,21 +447,59 @@ func (a *goApollo) Options() options.Options {
 
 // 启动goroutine去轮训apollo通知接口
 func (a *goApollo) Start() <-chan *LongPollerError {
+	return a.StartCtx(context.Background())
+}
+
+// StartCtx ctx被取消或者调用Stop时，停止轮训并立即中断正在被hold的长轮训请求
+func (a *goApollo) StartCtx(ctx context.Context) <-chan *LongPollerError {
 	a.runOnce.Do(func() {
+		ctx, cancel := context.WithCancel(ctx)
 		go func() {
+			select {
+			case <-a.stopCh:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		go func() {
+			defer cancel()
+
 			timer := time.NewTimer(a.opts.LongPollerInterval)
 			defer timer.Stop()
 
 			for !a.shouldStop() {
 				select {
 				case <-timer.C:
-					a.longPoll()
-					timer.Reset(a.opts.LongPollerInterval)
-				case <-a.stopCh:
+					// 失败后按照重试策略逐渐增加轮训间隔，防止apollo不可用时所有实例每秒请求一次
+					if err := a.longPoll(ctx); err != nil {
+						if a.longPollBackoff.Exhausted() {
+							// 上一轮的尝试次数已用完，开始新的一轮
+							a.longPollBackoff.Reset()
+						}
+						delay := a.longPollBackoff.Fail(err)
+						if a.longPollBackoff.Exhausted() {
+							// 本轮尝试次数用完，等待MaxDelay后再开始新的一轮
+							delay = a.longPollBackoff.Policy().MaxDelay
+							a.logger().Error("long poll retries exhausted", "attempt", a.longPollBackoff.State().Attempt,
+								"delay", delay, "error", err)
+						}
+						timer.Reset(delay)
+					} else {
+						a.longPollBackoff.Reset()
+						timer.Reset(a.opts.LongPollerInterval)
+					}
+				case <-ctx.Done():
 					return
 				}
 			}
 		}()
+
+		if a.opts.RefreshInterval > 0 || a.opts.CacheRefreshInterval > 0 {
+			go a.refreshLoop(ctx)
+		}
+
+		a.startSubscriptions(ctx)
 	})
 
 	return a.errorsCh
@@ -300,25 +514,50 @@ func (a *goApollo) shouldStop() bool {
 	}
 }
 
-func (a *goApollo) longPoll() {
+func (a *goApollo) longPoll(ctx context.Context) error {
 	localNotifications := a.getLocalNotifications()
 
 	// 这里有个问题是非预加载的namespace，如果在Start开启监听后才被initNamespace
 	// 需要等待90秒后的下一次轮训才能收到事件通知
-	notifications, err := a.getRemoteNotifications(localNotifications)
+	start := time.Now()
+	status, notifications, err := a.getRemoteNotifications(ctx, localNotifications)
+	if ctx.Err() != nil {
+		// 停止轮训时中断的请求不作为错误上报
+		return nil
+	}
+	a.opts.Metrics.LongPoll(a.opts.Conf.AppID, a.opts.Conf.ClusterName, status, err, time.Since(start))
 	if err != nil {
-		a.sendErrorsCh("", nil, "", err)
-		return
+		// HTTP Status: 404时，apollo中不存在请求的appId或cluster
+		a.sendErrorsCh("", localNotifications, "", err)
+		return err
+	}
+
+	// HTTP Status: 304时，上报的namespace在hold期间没有更新的修改
+	if status == http.StatusNotModified {
+		return nil
 	}
 
 	// HTTP Status: 200时，正常返回notifications数据，数组含有需要更新namespace和notificationID
-	// HTTP Status: 304时，上报的namespace没有更新的修改，返回notifications为空数组，遍历空数组跳过
+	a.reloadLock.Lock()
+	defer a.reloadLock.Unlock()
+
+	var lastErr error
 	for _, notification := range notifications {
+		a.logger().Debug("namespace changed", "namespace", notification.NamespaceName,
+			"notification_id", notification.NotificationID)
+
 		// 读取旧缓存用来给监听队列
 		oldValue := a.getNameSpace(notification.NamespaceName)
 
 		// 更新namespace
-		_, newValue, err := a.reloadNamespace(a.balance, a.apolloClient, notification.NamespaceName)
+		status, newValue, err := a.reloadNamespaceWithRetry(ctx, notification.NamespaceName)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err == nil && status != http.StatusOK && status != http.StatusNotModified {
+			// 未能从apollo获取到最新的配置，不更新NotificationID，下一轮长轮训会继续重新加载
+			err = fmt.Errorf("apollo: reload namespace %s failed, status: %d", notification.NamespaceName, status)
+		}
 		if err == nil {
 			// 发送到监听channel
 			a.sendWatchCh(notification.NamespaceName, oldValue, newValue)
@@ -329,10 +568,98 @@ func (a *goApollo) longPoll() {
 			a.notificationMap.Store(notification.NamespaceName, notification.NotificationID)
 		} else {
 			a.sendErrorsCh("", notifications, notification.NamespaceName, err)
+			lastErr = err
+		}
+	}
+
+	return lastErr
+}
+
+// getConfigsFromCache 通过/configfiles/json接口获取配置，该接口不返回release key，
+// 与当前配置一致时视为304，否则视为200并沿用当前的release key
+func (a *goApollo) getConfigsFromCache(ctx context.Context, cacheClient client.ICacheClient, clientConf config.Config, releaseKey string) (int, *client.NonCacheResp, error) {
+	configurations, err := cacheClient.GetConfigsFromCacheCtx(ctx, clientConf)
+	if err != nil {
+		var statusErr *client.StatusError
+		if errors.As(err, &statusErr) {
+			return statusErr.StatusCode, nil, nil
+		}
+		return 0, nil, err
+	}
+
+	// 该接口返回的是未解析的配置，需要与未解析的当前配置比较
+	current, found := a.rawConfigurations(clientConf.NamespaceName)
+	if entry, ok := a.restored.Load(clientConf.NamespaceName); ok {
+		current, found = entry.(*backup.Entry).Configurations, true
+	}
+	if found && reflect.DeepEqual(current, *configurations) {
+		return http.StatusNotModified, nil, nil
+	}
+
+	return http.StatusOK, &client.NonCacheResp{
+		AppID:          clientConf.AppID,
+		Cluster:        clientConf.ClusterName,
+		NamespaceName:  clientConf.NamespaceName,
+		Configurations: *configurations,
+		ReleaseKey:     releaseKey,
+	}, nil
+}
+
+// reloadNamespaceWithRetry 在同一轮内按照重试策略重新加载namespace，最多尝试RetryPolicy.MaxAttempts次
+func (a *goApollo) reloadNamespaceWithRetry(ctx context.Context, namespace string) (status int, conf config.Configurations, err error) {
+	b := backoff.New(a.opts.RetryPolicy)
+	for {
+		status, conf, err = a.reloadNamespace(ctx, a.balance, a.apolloClient, namespace)
+		if !shouldRetry(status, err) {
+			return
+		}
+
+		if err == nil {
+			err = fmt.Errorf("apollo: reload namespace %s failed, status: %d", namespace, status)
+		}
+		delay := b.Fail(err)
+		if b.Exhausted() {
+			return
+		}
+
+		a.logger().Warn("retry reload namespace", "namespace", namespace,
+			"status", status, "attempt", b.State().Attempt, "delay", delay, "error", err)
+		if backoff.Sleep(ctx, delay) != nil {
+			return
 		}
 	}
 }
 
+// shouldRetry 网络异常及apollo服务端异常时重试，304、404等确定的结果不需要重试
+func shouldRetry(status int, err error) bool {
+	if status >= http.StatusBadRequest && status < http.StatusInternalServerError {
+		return false
+	}
+	return err != nil || status == 0 || status >= http.StatusInternalServerError
+}
+
+// report 将请求结果上报给实现了balancer.Reporter的Balancer，ctx被取消导致的失败不计入
+func report(ctx context.Context, balance balancer.Balancer, url string, status int, err error) {
+	reporter, ok := balance.(balancer.Reporter)
+	if !ok || ctx.Err() != nil {
+		return
+	}
+
+	if shouldRetry(status, err) {
+		if err == nil {
+			err = fmt.Errorf("unexpected response status %d", status)
+		}
+		reporter.Report(url, err)
+		return
+	}
+	reporter.Report(url, nil)
+}
+
+// BackoffState 返回长轮训当前的退避状态，用于诊断apollo的连接情况
+func (a *goApollo) BackoffState() backoff.State {
+	return a.longPollBackoff.State()
+}
+
 func (a *goApollo) Stop() {
 	a.stopLock.Lock()
 	defer a.stopLock.Unlock()
@@ -340,15 +667,24 @@ func (a *goApollo) Stop() {
 		return
 	}
 
-	if a.balance != nil {
+	a.stopSubscriptions()
+	a.unsubscribe()
+
+	// 订阅的appID与创建它的goApollo共用负载均衡
+	if a.balance != nil && a.parent == nil {
 		a.balance.Stop()
 	}
 
+	a.closeListeners()
+
 	a.stop = true
+	a.cancel()
 	close(a.stopCh)
 }
 
 func (a *goApollo) Watch() <-chan *ApolloResponse {
+	a.watchChLock.Lock()
+	defer a.watchChLock.Unlock()
 	if a.watchCh == nil {
 		a.watchCh = make(chan *ApolloResponse)
 	}
@@ -356,13 +692,20 @@ func (a *goApollo) Watch() <-chan *ApolloResponse {
 	return a.watchCh
 }
 
+// getWatchCh 返回Watch创建的channel，未调用Watch时返回nil
+func (a *goApollo) getWatchCh() chan *ApolloResponse {
+	a.watchChLock.Lock()
+	defer a.watchChLock.Unlock()
+	return a.watchCh
+}
+
 func (a *goApollo) WatchNamespace(namespace string, stop chan bool) <-chan *ApolloResponse {
 	watchNamespace := fixWatchNamespace(namespace)
 	watchCh, exists := a.watchNamespaceChMap.LoadOrStore(watchNamespace, make(chan *ApolloResponse))
 	if !exists {
 		go func() {
 			// 非预加载以外的namespace,初始化基础meta信息,否则没有longpoll
-			err := a.initNamespace(namespace)
+			err := a.initNamespace(a.ctx, namespace)
 			if err != nil {
 				watchCh.(chan *ApolloResponse) <- &ApolloResponse{
 					Namespace: namespace,
@@ -391,24 +734,46 @@ func fixWatchNamespace(namespace string) string {
 }
 
 func (a *goApollo) sendWatchCh(namespace string, oldVal, newVal config.Configurations) {
+	oldVal, newVal = a.applyOverrides(namespace, oldVal), a.applyOverrides(namespace, newVal)
+	if a.opts.Interpolate {
+		a.sendInterpolatedWatchCh(namespace, oldVal, newVal)
+		return
+	}
+	a.emitWatchCh(namespace, oldVal, newVal)
+}
+
+func (a *goApollo) emitWatchCh(namespace string, oldVal, newVal config.Configurations) {
 	changes := oldVal.Different(newVal)
 	if len(changes) == 0 {
 		return
 	}
 
+	// 重新解析绑定到该namespace的结构体
+	a.updateBindings(namespace, newVal)
+
 	resp := &ApolloResponse{
+		AppID:     a.opts.Conf.AppID,
+		Cluster:   a.opts.Conf.ClusterName,
 		Namespace: namespace,
 		OldValue:  oldVal,
 		NewValue:  newVal,
 		Changes:   changes,
 	}
 
+	// 监听器拥有独立的队列，不受下面channel超时的影响
+	a.sendListeners(resp)
+	if a.parent != nil {
+		// 与Watch一致，Subscribe订阅的变更同样通知创建它的goApollo的监听器
+		a.parent.sendListeners(resp)
+	}
+
 	timer := time.NewTimer(defaultWatchTimeout)
 	for _, watchCh := range a.getWatchChs(namespace) {
 		select {
 		case watchCh <- resp:
 
 		case <-timer.C: // 防止创建全局监听或者某个namespace监听却不消费死锁问题
+			a.opts.Metrics.WatchDropped(a.opts.Conf.AppID, a.opts.Conf.ClusterName, namespace)
 			timer.Reset(defaultWatchTimeout)
 		}
 	}
@@ -416,8 +781,13 @@ func (a *goApollo) sendWatchCh(namespace string, oldVal, newVal config.Configura
 
 func (a *goApollo) getWatchChs(namespace string) []chan *ApolloResponse {
 	var chs []chan *ApolloResponse
-	if a.watchCh != nil {
-		chs = append(chs, a.watchCh)
+	if watchCh := a.getWatchCh(); watchCh != nil {
+		chs = append(chs, watchCh)
+	}
+	if a.parent != nil {
+		if watchCh := a.parent.getWatchCh(); watchCh != nil {
+			chs = append(chs, watchCh)
+		}
 	}
 
 	watchNamespace := fixWatchNamespace(namespace)
@@ -439,6 +809,7 @@ func (a *goApollo) sendErrorsCh(configServerURL string, notifications []config.N
 		Namespace:       namespace,
 		Err:             err,
 	}
+	a.recordError(longPollerError)
 	select {
 	case a.errorsCh <- longPollerError:
 
@@ -447,63 +818,96 @@ func (a *goApollo) sendErrorsCh(configServerURL string, notifications []config.N
 	}
 }
 
-func (a *goApollo) log(kvs ...interface{}) {
-	a.opts.Logger.Log(
-		append([]interface{}{
-			"[GoApollo]", "",
-			"AppID", a.opts.Conf.AppID,
-			"Cluster", a.opts.Conf.ClusterName,
-		},
-			kvs...,
-		)...,
-	)
+// logger 返回带有app_id及cluster字段的Logger
+func (a *goApollo) logger() log.Logger {
+	return log.With(a.opts.Logger, "app_id", a.opts.Conf.AppID, "cluster", a.opts.Conf.ClusterName)
 }
 
-func (a *goApollo) backup() error {
-	backup := map[string]config.Configurations{}
-	a.cache.Range(func(key, val interface{}) bool {
-		k, _ := key.(string)
-		conf, _ := val.(config.Configurations)
-		backup[k] = conf
-		return true
+// backup 备份namespace当前的配置、release key及notificationID
+func (a *goApollo) backup(namespace string) error {
+	releaseKey, _ := a.releaseKeyMap.Load(namespace)
+	notificationID, _ := a.notificationMap.Load(namespace)
+	rk, _ := releaseKey.(string)
+	id, ok := notificationID.(int)
+	if !ok {
+		id = defaultNotificationID
+	}
+	// 备份未解析的配置，与apollo返回的格式一致
+	raw, _ := a.rawConfigurations(namespace)
+
+	err := backup.SaveEntry(a.opts.BackupStore, &backup.Entry{
+		Namespace:      namespace,
+		ReleaseKey:     rk,
+		Configurations: raw,
+		NotificationID: id,
+		AppID:          a.opts.Conf.AppID,
+		Cluster:        a.opts.Conf.ClusterName,
+		FetchedAt:      time.Now(),
 	})
+	a.opts.Metrics.Backup(a.opts.Conf.AppID, a.opts.Conf.ClusterName, namespace, options.BackupSave, err)
+	return err
+}
 
-	data, err := json.Marshal(backup)
-	if err != nil {
-		return err
+// touchBackup 配置未变化时仅更新备份的FetchedAt及notificationID，备份与缓存不一致时重新备份
+func (a *goApollo) touchBackup(namespace string) error {
+	if _, ok := a.opts.BackupStore.(backup.EntrySaver); !ok {
+		// 不保存元数据的store没有需要更新的内容
+		return nil
 	}
 
-	err = os.MkdirAll(filepath.Dir(a.opts.BackupFile), 0777)
-	if err != nil && !os.IsExist(err) {
-		return err
+	releaseKey, _ := a.releaseKeyMap.Load(namespace)
+	entry, err := a.opts.BackupStore.Load(namespace)
+	if err != nil || entry.ReleaseKey != releaseKey || entry.AppID != a.opts.Conf.AppID || entry.Cluster != a.opts.Conf.ClusterName {
+		return a.backup(namespace)
 	}
 
-	return ioutil.WriteFile(a.opts.BackupFile, data, 0666)
+	// 初始化时还没有notificationID，保留备份中的
+	if notificationID, ok := a.notificationMap.Load(namespace); ok {
+		entry.NotificationID = notificationID.(int)
+	}
+	entry.FetchedAt = time.Now()
+	err = backup.SaveEntry(a.opts.BackupStore, entry)
+	a.opts.Metrics.Backup(a.opts.Conf.AppID, a.opts.Conf.ClusterName, namespace, options.BackupSave, err)
+	return err
 }
 
-func (a *goApollo) loadBackup(specifyNamespace string) (config.Configurations, error) {
-	if _, err := os.Stat(a.opts.BackupFile); err != nil {
-		return nil, err
+func (a *goApollo) logBackupError(namespace string, err error) {
+	if err != nil {
+		a.logger().Warn("save backup failed", "namespace", namespace, "error", err)
 	}
+}
 
-	data, err := ioutil.ReadFile(a.opts.BackupFile)
+// loadBackup 读取容灾备份，超过MaxBackupAge的备份返回backup.ErrExpired
+func (a *goApollo) loadBackup(namespace string) (config.Configurations, error) {
+	entry, err := a.opts.BackupStore.Load(namespace)
 	if err != nil {
 		return nil, err
 	}
 
-	backup := map[string]config.Configurations{}
-	err = json.Unmarshal(data, &backup)
-	if err != nil {
-		return nil, err
+	if entry.Expired(a.opts.MaxBackupAge, time.Now()) {
+		return nil, fmt.Errorf("%w: namespace %s fetched at %s", backup.ErrExpired, namespace, entry.FetchedAt.Format(time.RFC3339))
 	}
 
-	for namespace, configs := range backup {
-		if namespace == specifyNamespace {
-			return configs, nil
+	return entry.Configurations, nil
+}
+
+// restoreBackup 从同一appID及cluster的备份中恢复release key，使启动时的第一次请求可以返回304
+func (a *goApollo) restoreBackup(namespace string) *backup.Entry {
+	entry, err := a.opts.BackupStore.Load(namespace)
+	if err != nil {
+		if err != backup.ErrNotFound {
+			a.logger().Warn("restore backup failed", "namespace", namespace, "error", err)
 		}
+		return nil
 	}
 
-	return nil, nil
+	if entry.ReleaseKey == "" || entry.AppID != a.opts.Conf.AppID || entry.Cluster != a.opts.Conf.ClusterName {
+		return nil
+	}
+
+	a.releaseKeyMap.Store(namespace, entry.ReleaseKey)
+	a.restored.Store(namespace, entry)
+	return entry
 }
 
 // getRemoteNotifications
@@ -512,21 +916,22 @@ func (a *goApollo) loadBackup(specifyNamespace string) (config.Configurations, e
 // 请求被hold 90秒的情况:
 // 1. 请求的notificationID和apollo服务器中的ID相等
 // 2. 请求的namespace都是在apollo中不存在的
-func (a *goApollo) getRemoteNotifications(req []config.Notification) (notifies []config.Notification, err error) {
+func (a *goApollo) getRemoteNotifications(ctx context.Context, req []config.Notification) (status int, notifies []config.Notification, err error) {
 	clientConf := a.opts.Conf
 	clientConf.ConfigServerUrl, err = a.balance.Select()
 	clientConf.Notifications = req
 	if err != nil {
-		a.log("ConfigServerUrl", clientConf.ConfigServerUrl, "Error", err, "Action", "Balancer.Select")
+		a.logger().Error("select config server failed", "error", err)
 		return
 	}
+	a.selected(clientConf.ConfigServerUrl)
 
-	status, err := a.apolloClient.GetNotifications(clientConf, notifies)
+	status, notifies, err = a.apolloClient.GetNotificationsCtx(ctx, clientConf)
+	report(ctx, a.balance, clientConf.ConfigServerUrl, status, err)
 	if err != nil {
-		a.log("ConfigServerUrl", clientConf.ConfigServerUrl,
-			"GetNotifications", req, "ServerResponseStatus", status,
-			"Error", err, "Action", "LongPoll")
-		return nil, err
+		a.logger().Warn("long poll failed", "config_server", clientConf.ConfigServerUrl,
+			"notifications", req, "status", status, "error", err)
+		return status, nil, err
 	}
 
 	return
@@ -566,6 +971,10 @@ func Start() <-chan *LongPollerError {
 	return defaultGoApollo.Start()
 }
 
+func StartCtx(ctx context.Context) <-chan *LongPollerError {
+	return defaultGoApollo.StartCtx(ctx)
+}
+
 func Stop() {
 	defaultGoApollo.Stop()
 }
@@ -578,6 +987,22 @@ func GetNameSpace(namespace string) config.Configurations {
 	return defaultGoApollo.GetNameSpace(namespace)
 }
 
+func Chain(namespaces ...string) (*NamespaceChain, error) {
+	return defaultGoApollo.Chain(namespaces...)
+}
+
+func Ready() error {
+	return defaultGoApollo.Ready()
+}
+
+func WaitReady(ctx context.Context, namespaces ...string) error {
+	return defaultGoApollo.WaitReady(ctx, namespaces...)
+}
+
+func AdminHandler() http.Handler {
+	return defaultGoApollo.AdminHandler()
+}
+
 func Watch() <-chan *ApolloResponse {
 	return defaultGoApollo.Watch()
 }
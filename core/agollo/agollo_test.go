@@ -1,23 +1,32 @@
 package agollo
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"math/rand"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"strconv"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/sixgoatsh/agollo/core/apollotest"
+	"github.com/sixgoatsh/agollo/core/backup"
 	"github.com/sixgoatsh/agollo/core/client"
 	"github.com/sixgoatsh/agollo/core/client/balancer"
 	"github.com/sixgoatsh/agollo/core/config"
+	"github.com/sixgoatsh/agollo/core/metrics"
 	"github.com/sixgoatsh/agollo/core/mock"
 	"github.com/sixgoatsh/agollo/core/options"
+	"github.com/sixgoatsh/agollo/pkg/backoff"
 	"github.com/sixgoatsh/agollo/pkg/log"
 )
 
@@ -41,6 +50,22 @@ func TestAgollo(t *testing.T) {
 				},
 				ReleaseKey: "111",
 			},
+			"typed": {
+				AppID:         appid,
+				Cluster:       cluster,
+				NamespaceName: "typed",
+				Configurations: map[string]interface{}{
+					"int":      "10",
+					"bool":     "true",
+					"float":    "1.5",
+					"duration": "3s",
+					"time":     "2020-01-02T15:04:05Z",
+					"slice":    "a;b;c",
+					"map":      `{"name":"foo"}`,
+					"bad":      "bad",
+				},
+				ReleaseKey: "131",
+			},
 			"test.json": {
 				AppID:         appid,
 				Cluster:       cluster,
@@ -92,21 +117,21 @@ func TestAgollo(t *testing.T) {
 
 	newNotificationClient := func(configs map[string]*client.NonCacheResp) client.INotificationClient {
 		return &mock.NotificationsClient{
-			Notifications: func(conf config.Config, result []config.Notification) (status int, err error) {
+			Notifications: func(conf config.Config) (status int, notifications []config.Notification, err error) {
 				rk, _ := strconv.Atoi(configs["application"].ReleaseKey)
 				n := rand.Intn(2)
 				if n%2 == 0 {
 					rk++
 					configs["application"].ReleaseKey = fmt.Sprint(rk)
 				}
-				result = []config.Notification{
+				notifications = []config.Notification{
 					{
 						NamespaceName:  "application",
 						NotificationID: rk,
 					},
 				}
 
-				return 200, nil
+				return 200, notifications, nil
 			},
 		}
 	}
@@ -132,8 +157,8 @@ func TestAgollo(t *testing.T) {
 
 	badNotificationClient := func(configs map[string]*client.NonCacheResp) client.INotificationClient {
 		return &mock.NotificationsClient{
-			Notifications: func(conf config.Config, result []config.Notification) (status int, err error) {
-				return 500, nil
+			Notifications: func(conf config.Config) (status int, notifications []config.Notification, err error) {
+				return 500, nil, fmt.Errorf("apollo: unexpected notifications response status %d", 500)
 			},
 		}
 	}
@@ -205,6 +230,47 @@ func TestAgollo(t *testing.T) {
 				assert.Equal(t, expected, actual)
 			},
 		},
+		{
+			Name: "测试：类型化Get方法正常转换配置值，转换失败时返回默认值",
+			Test: func(configs map[string]*client.NonCacheResp) {
+				backupFile, err := ioutil.TempFile("", "backup")
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer os.Remove(backupFile.Name())
+				ba, _ := defaultBalance(configServerURL, appid, newMetaClient)
+				a, err := NewGoApollo(configServerURL, appid,
+					client.NewApolloClient(newMetaClient, newNonCacheClient(configs), newCacheClient, newNotificationClient(configs)),
+					ba,
+					options.PreloadNamespaces("typed"),
+					options.BackupFile(backupFile.Name()),
+				)
+				assert.Nil(t, err)
+				assert.NotNil(t, a)
+
+				ns := options.WithNamespace("typed")
+				assert.Equal(t, 10, a.GetInt("int", ns))
+				assert.Equal(t, int64(10), a.GetInt64("int", ns))
+				assert.Equal(t, true, a.GetBool("bool", ns))
+				assert.Equal(t, 1.5, a.GetFloat64("float", ns))
+				assert.Equal(t, 3*time.Second, a.GetDuration("duration", ns))
+				assert.Equal(t, time.Date(2020, 1, 2, 15, 4, 5, 0, time.UTC), a.GetTime("time", ns))
+				assert.Equal(t, []string{"a", "b", "c"}, a.GetStringSlice("slice", ns, options.WithSeparator(";")))
+				assert.Equal(t, map[string]interface{}{"name": "foo"}, a.GetStringMap("map", ns))
+
+				// key不存在时转换默认值
+				assert.Equal(t, 20, a.GetInt("missing", ns, options.WithDefault("20")))
+				assert.Equal(t, 0, a.GetInt("missing", ns))
+
+				// 转换失败时，E方法返回错误，非E方法返回默认值
+				_, err = a.GetIntE("bad", ns)
+				assert.NotNil(t, err)
+				assert.Equal(t, 30, a.GetInt("bad", ns, options.WithDefault("30")))
+				_, err = a.GetBoolE("bad", ns)
+				assert.NotNil(t, err)
+				assert.Equal(t, false, a.GetBool("bad", ns))
+			},
+		},
 		{
 			Name: "测试：初始化后 start 监听配置的情况",
 			Test: func(configs map[string]*client.NonCacheResp) {
@@ -317,3 +383,347 @@ func TestAgollo(t *testing.T) {
 func defaultBalance(configServerURL, appID string, serverClient client.IMetaServerClient) (balancer.Balancer, error) {
 	return balancer.NewBalancer(config.DefaultConfig(configServerURL, appID), false, 0, nil, serverClient)
 }
+
+// newTestApollo 创建连接到serverURL的GoApollo，appID为test，默认不保存备份，opts可以覆盖默认的配置
+func newTestApollo(t *testing.T, serverURL string, opts ...options.Option) (GoApollo, error) {
+	t.Helper()
+	return NewGoApollo(serverURL, "test",
+		client.New(),
+		balancer.NewRoundRobin([]string{serverURL}),
+		append([]options.Option{options.WithBackupStore(backup.NewNopStore())}, opts...)...,
+	)
+}
+
+func TestLongPollWithServer(t *testing.T) {
+	appid := "test"
+	server := apollotest.NewServer(apollotest.Hold(500 * time.Millisecond))
+	server.Publish(appid, "default", "application", config.Configurations{"timeout": "100"})
+	defer server.Close()
+
+	a, err := newTestApollo(t, server.URL,
+		options.PreloadNamespaces("application"),
+		options.LongPollerInterval(10*time.Millisecond),
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, "100", a.Get("timeout"))
+
+	watchCh := a.Watch()
+	errorsCh := a.Start()
+	defer a.Stop()
+
+	// 等待长轮训请求被hold后再发布
+	time.Sleep(100 * time.Millisecond)
+	server.Publish(appid, "default", "application", config.Configurations{"timeout": "200", "retry": "3"})
+
+	select {
+	case resp := <-watchCh:
+		assert.Equal(t, "application", resp.Namespace)
+		assert.Equal(t, config.Configurations{"timeout": "100"}, resp.OldValue)
+		assert.Equal(t, config.Configurations{"timeout": "200", "retry": "3"}, resp.NewValue)
+		assert.Equal(t, config.Changes{
+			config.NewChange(config.ChangeTypeAdd, "retry", "3"),
+			config.NewChange(config.ChangeTypeUpdate, "timeout", "200"),
+		}, resp.Changes)
+	case err := <-errorsCh:
+		t.Fatal(err.Err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("release should reach Watch() subscribers")
+	}
+
+	assert.Equal(t, "200", a.Get("timeout"))
+}
+
+func TestMetrics(t *testing.T) {
+	appid := "test"
+	server := apollotest.NewServer(apollotest.Hold(500 * time.Millisecond))
+	defer server.Close()
+	server.Publish(appid, "default", "application", config.Configurations{"timeout": "100"})
+
+	m := metrics.New()
+	a, err := newTestApollo(t, server.URL,
+		options.PreloadNamespaces("application"),
+		options.WithBackupStore(backup.NewMemoryStore()),
+		options.LongPollerInterval(10*time.Millisecond),
+		options.WithMetrics(m),
+	)
+	assert.Nil(t, err)
+
+	assert.Equal(t, float64(1), m.Value("agollo_fetch_total", appid, "default", "application", "non-cache", "200"))
+	// 读取配置后保存备份，获取notificationID后补充备份
+	assert.Equal(t, float64(2), m.Value("agollo_backup_total", appid, "default", "application", "save", "success"))
+	assert.Equal(t, float64(2), m.Value("agollo_balancer_select_total", server.URL))
+	assert.InDelta(t, float64(time.Now().Unix()),
+		m.Value("agollo_last_sync_timestamp_seconds", appid, "default", "application"), 60)
+
+	a.Start()
+	defer a.Stop()
+
+	server.Publish(appid, "default", "application", config.Configurations{"timeout": "200"})
+	assert.Eventually(t, func() bool {
+		return m.Value("agollo_long_poll_total", appid, "default", "changed") >= 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	var b strings.Builder
+	_, err = m.WriteTo(&b)
+	assert.Nil(t, err)
+	assert.Contains(t, b.String(), "# TYPE agollo_long_poll_duration_seconds histogram")
+	assert.Contains(t, b.String(), `agollo_long_poll_duration_seconds_count{app_id="test",cluster="default"}`)
+}
+
+func TestStopAbortsLongPoll(t *testing.T) {
+	appid := "test"
+	server := apollotest.NewServer(apollotest.Hold(time.Minute))
+	server.Publish(appid, "default", "application", config.Configurations{"timeout": "100"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	a, err := NewGoApolloCtx(ctx, server.URL, appid,
+		client.New(),
+		balancer.NewRoundRobin([]string{server.URL}),
+		options.PreloadNamespaces("application"),
+		options.WithBackupStore(backup.NewNopStore()),
+		options.LongPollerInterval(10*time.Millisecond),
+	)
+	assert.Nil(t, err)
+
+	a.Start()
+	// 等待长轮训请求被hold
+	time.Sleep(200 * time.Millisecond)
+
+	start := time.Now()
+	a.Stop()
+	// server.Close会等待所有请求结束，被hold的请求需要被客户端中断才能立即返回
+	server.Close()
+	assert.True(t, time.Since(start) < 5*time.Second, "Stop should abort the held long poll")
+
+	// 通过ctx停止轮训
+	server = apollotest.NewServer(apollotest.Hold(time.Minute))
+	server.Publish(appid, "default", "application", config.Configurations{"timeout": "100"})
+	defer server.Close()
+
+	a, err = newTestApollo(t, server.URL,
+		options.PreloadNamespaces("application"),
+		options.LongPollerInterval(10*time.Millisecond),
+	)
+	assert.Nil(t, err)
+	defer a.Stop()
+
+	pollCtx, pollCancel := context.WithCancel(context.Background())
+	errorsCh := a.StartCtx(pollCtx)
+	time.Sleep(200 * time.Millisecond)
+	pollCancel()
+
+	select {
+	case err := <-errorsCh:
+		t.Fatalf("canceled long poll should not be reported: %v", err.Err)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestRetry(t *testing.T) {
+	configServerURL := "http://localhost:8080"
+	appid := "test"
+	policy := options.WithRetryPolicy(backoff.Policy{
+		InitialDelay: 100 * time.Millisecond,
+		MaxDelay:     time.Second,
+		Multiplier:   2,
+		MaxAttempts:  3,
+	})
+
+	backupFile, err := ioutil.TempFile("", "backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(backupFile.Name())
+
+	// 长轮训失败后逐渐增加轮训间隔
+	var (
+		mu            sync.Mutex
+		notifyCalls   int
+		reloadCalls   int
+		notifyFailing = true
+	)
+	notificationClient := &mock.NotificationsClient{
+		Notifications: func(conf config.Config) (int, []config.Notification, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			notifyCalls++
+			if notifyFailing {
+				return 0, nil, fmt.Errorf("connection refused")
+			}
+			return 200, []config.Notification{{NamespaceName: "application", NotificationID: 2}}, nil
+		},
+	}
+	nonCacheClient := &mock.NonCacheClient{
+		ConfigsFromNonCache: func(conf config.Config, opts ...client.NotificationsOption) (int, *client.NonCacheResp, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			reloadCalls++
+			// 初始化成功，之后前两次重新加载失败
+			switch reloadCalls {
+			case 1:
+				return 200, &client.NonCacheResp{Configurations: config.Configurations{"timeout": "100"}, ReleaseKey: "1"}, nil
+			case 2, 3:
+				return 503, nil, nil
+			default:
+				return 200, &client.NonCacheResp{Configurations: config.Configurations{"timeout": "200"}, ReleaseKey: "2"}, nil
+			}
+		},
+	}
+
+	ba, _ := defaultBalance(configServerURL, appid, &mock.MetaServerClient{})
+	a, err := NewGoApollo(configServerURL, appid,
+		client.NewApolloClient(&mock.MetaServerClient{}, nonCacheClient, &mock.CacheClient{}, notificationClient),
+		ba,
+		policy,
+		options.PreloadNamespaces("application"),
+		options.BackupFile(backupFile.Name()),
+		options.LongPollerInterval(10*time.Millisecond),
+	)
+	assert.Nil(t, err)
+
+	mu.Lock()
+	notifyCalls = 0
+	mu.Unlock()
+
+	watchCh := a.Watch()
+	a.Start()
+	defer a.Stop()
+
+	// 无退避时700ms内会请求约70次，退避后仅在10ms、110ms、310ms附近请求，
+	// 第3次失败后本轮尝试次数用完，等待MaxDelay后才开始下一轮
+	time.Sleep(700 * time.Millisecond)
+	mu.Lock()
+	assert.True(t, notifyCalls <= 3, "long poll should back off, calls: %d", notifyCalls)
+	notifyFailing = false
+	mu.Unlock()
+
+	state := a.BackoffState()
+	assert.Equal(t, 3, state.Attempt)
+	assert.NotNil(t, state.LastError)
+
+	// 恢复后namespace重新加载失败会在同一轮内重试
+	select {
+	case resp := <-watchCh:
+		assert.Equal(t, "200", resp.NewValue["timeout"])
+	case <-time.After(5 * time.Second):
+		t.Fatal("namespace should be reloaded after retry")
+	}
+
+	mu.Lock()
+	assert.Equal(t, 4, reloadCalls)
+	mu.Unlock()
+	assert.Eventually(t, func() bool {
+		return a.BackoffState().Attempt == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestRestoreFromBackup(t *testing.T) {
+	appid := "test"
+	server := apollotest.NewServer(apollotest.Hold(500 * time.Millisecond))
+	defer server.Close()
+	releaseKey := server.Publish(appid, "default", "application", config.Configurations{"timeout": "100"})
+
+	store := backup.NewMemoryStore()
+	a, err := newTestApollo(t, server.URL, options.PreloadNamespaces("application"), options.WithBackupStore(store))
+	assert.Nil(t, err)
+	a.Stop()
+
+	entry, err := store.Load("application")
+	assert.Nil(t, err)
+	assert.Equal(t, releaseKey, entry.ReleaseKey)
+	assert.Equal(t, 1, entry.NotificationID)
+	assert.Equal(t, appid, entry.AppID)
+	assert.Equal(t, "default", entry.Cluster)
+	assert.WithinDuration(t, time.Now(), entry.FetchedAt, time.Minute)
+
+	// 重启后使用备份中的release key，apollo返回304，也不需要再获取notificationID
+	server.ResetRequests()
+	entry.FetchedAt = time.Now().Add(-time.Hour)
+	assert.Nil(t, backup.SaveEntry(store, entry))
+
+	a, err = newTestApollo(t, server.URL, options.PreloadNamespaces("application"), options.WithBackupStore(store))
+	assert.Nil(t, err)
+	defer a.Stop()
+	assert.Equal(t, "100", a.Get("timeout"))
+
+	requests := server.Requests()
+	assert.Len(t, requests, 1)
+	assert.Equal(t, "/configs/test/default/application", requests[0].Path)
+
+	// 304同样刷新备份的同步时间，保留备份中的notificationID
+	entry, err = store.Load("application")
+	assert.Nil(t, err)
+	assert.Equal(t, releaseKey, entry.ReleaseKey)
+	assert.Equal(t, 1, entry.NotificationID)
+	assert.WithinDuration(t, time.Now(), entry.FetchedAt, time.Minute)
+}
+
+func TestMaxBackupAge(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	store := backup.NewMemoryStore()
+	err := backup.SaveEntry(store, &backup.Entry{
+		Namespace:      "application",
+		Configurations: config.Configurations{"timeout": "100"},
+		FetchedAt:      time.Now().Add(-2 * time.Hour),
+	})
+	assert.Nil(t, err)
+
+	fallback := []options.Option{
+		options.PreloadNamespaces("application"),
+		options.WithBackupStore(store),
+		options.FailTolerantOnBackupExists(),
+	}
+
+	a, err := newTestApollo(t, server.URL, append(fallback, options.MaxBackupAge(time.Hour))...)
+	assert.True(t, errors.Is(err, backup.ErrExpired))
+	assert.Equal(t, "", a.Get("timeout"))
+	a.Stop()
+
+	a, err = newTestApollo(t, server.URL, append(fallback, options.MaxBackupAge(3*time.Hour))...)
+	assert.Nil(t, err)
+	assert.Equal(t, "100", a.Get("timeout"))
+	a.Stop()
+}
+
+func TestLocalDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "local")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := dir + "/application.properties"
+	assert.Nil(t, ioutil.WriteFile(filename, []byte("timeout=100\n"), 0666))
+
+	os.Setenv(options.EnvLocalDir, dir)
+	defer os.Unsetenv(options.EnvLocalDir)
+
+	a, err := NewGoApollo("", "test", nil, nil,
+		options.PreloadNamespaces("application"),
+		options.LongPollerInterval(10*time.Millisecond),
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, "100", a.Get("timeout"))
+
+	watchCh := a.Watch()
+	errorsCh := a.Start()
+	defer a.Stop()
+
+	assert.Nil(t, ioutil.WriteFile(filename, []byte("timeout=200\n"), 0666))
+
+	select {
+	case resp := <-watchCh:
+		assert.Equal(t, "application", resp.Namespace)
+		assert.Equal(t, config.Configurations{"timeout": "200"}, resp.NewValue)
+	case err := <-errorsCh:
+		t.Fatal(err.Err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for local file change")
+	}
+	assert.Equal(t, "200", a.Get("timeout"))
+}
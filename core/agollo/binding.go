@@ -0,0 +1,130 @@
+package agollo
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+
+	"github.com/sixgoatsh/agollo/core/config"
+)
+
+// Binding 绑定到某个namespace的结构体，长轮训收到该namespace的变更后重新解析配置
+// 每次解析都会生成新的结构体，Load返回的快照在生成后不会再被修改，
+// Load是唯一可以并发安全读取最新配置的方式，Bind时传入的结构体只在Bind时赋值一次
+type Binding struct {
+	namespace string
+	typ       reflect.Type
+	val       atomic.Value
+
+	errMu sync.RWMutex
+	err   error
+
+	unbind func()
+}
+
+func newBinding(namespace string, typ reflect.Type) *Binding {
+	return &Binding{
+		namespace: namespace,
+		typ:       typ,
+	}
+}
+
+// Load 返回最近一次成功解析的结构体指针，类型与Bind时传入的指针一致，返回的结构体不能修改
+func (b *Binding) Load() interface{} {
+	return b.val.Load()
+}
+
+// Err 返回最近一次解析配置时发生的错误，解析成功后会被清空
+func (b *Binding) Err() error {
+	b.errMu.RLock()
+	defer b.errMu.RUnlock()
+	return b.err
+}
+
+// Unbind 停止跟随namespace的变更更新结构体
+func (b *Binding) Unbind() {
+	if b.unbind != nil {
+		b.unbind()
+	}
+}
+
+func (b *Binding) update(conf config.Configurations) error {
+	v := reflect.New(b.typ)
+	err := conf.Unmarshal(v.Interface())
+
+	b.errMu.Lock()
+	b.err = err
+	b.errMu.Unlock()
+
+	if err != nil {
+		// 解析失败时保留上一次的快照
+		return err
+	}
+
+	b.val.Store(v.Interface())
+	return nil
+}
+
+func (a *goApollo) Unmarshal(namespace string, out interface{}) error {
+	return a.GetNameSpace(namespace).Unmarshal(out)
+}
+
+// Bind 跟随namespace的变更重新解析配置，最新的配置只能通过返回的Binding.Load读取
+// 注意：ptr仅是Bind时的初始快照，之后的变更不会写回ptr，一直读取ptr会得到过期的配置，
+// 这样长轮训的goroutine不会与调用方同时读写ptr
+// 非预加载的namespace会在Bind时初始化，初始化失败时仍然会返回Binding，待apollo恢复后继续跟随变更
+func (a *goApollo) Bind(namespace string, ptr interface{}) (*Binding, error) {
+	v := reflect.ValueOf(ptr)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil, config.ErrInvalidUnmarshalTarget
+	}
+
+	// 非预加载以外的namespace,初始化基础meta信息,否则没有longpoll
+	initErr := a.initNamespace(a.ctx, namespace)
+
+	b := newBinding(namespace, v.Type().Elem())
+	if err := b.update(a.view(namespace)); err != nil {
+		return nil, err
+	}
+	v.Elem().Set(reflect.ValueOf(b.Load()).Elem())
+
+	watchNamespace := fixWatchNamespace(namespace)
+	a.bindingsLock.Lock()
+	a.bindings[watchNamespace] = append(a.bindings[watchNamespace], b)
+	a.bindingsLock.Unlock()
+
+	b.unbind = func() {
+		a.bindingsLock.Lock()
+		defer a.bindingsLock.Unlock()
+		bs := a.bindings[watchNamespace]
+		for i := range bs {
+			if bs[i] == b {
+				a.bindings[watchNamespace] = append(bs[:i:i], bs[i+1:]...)
+				break
+			}
+		}
+	}
+
+	return b, initErr
+}
+
+func (a *goApollo) updateBindings(namespace string, conf config.Configurations) {
+	a.bindingsLock.RLock()
+	bs := a.bindings[fixWatchNamespace(namespace)]
+	a.bindingsLock.RUnlock()
+
+	for _, b := range bs {
+		if err := b.update(conf); err != nil {
+			a.logger().Error("update binding failed", "namespace", namespace, "error", err)
+		}
+	}
+}
+
+func Unmarshal(namespace string, out interface{}) error {
+	return defaultGoApollo.Unmarshal(namespace, out)
+}
+
+// Bind 使用默认的GoApollo绑定namespace，ptr仅是Bind时的初始快照，最新的配置通过Binding.Load读取
+func Bind(namespace string, ptr interface{}) (*Binding, error) {
+	return defaultGoApollo.Bind(namespace, ptr)
+}
@@ -0,0 +1,91 @@
+package agollo
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sixgoatsh/agollo/core/client"
+	"github.com/sixgoatsh/agollo/core/config"
+	"github.com/sixgoatsh/agollo/core/mock"
+	"github.com/sixgoatsh/agollo/core/options"
+)
+
+func TestBind(t *testing.T) {
+	configServerURL := "http://localhost:8080"
+	appid := "test"
+	resp := &client.NonCacheResp{
+		AppID:          appid,
+		NamespaceName:  "application",
+		Configurations: config.Configurations{"timeout": "100", "db.host": "127.0.0.1"},
+		ReleaseKey:     "1",
+	}
+	var mu sync.Mutex
+	apolloClient := client.NewApolloClient(
+		&mock.MetaServerClient{},
+		&mock.NonCacheClient{
+			ConfigsFromNonCache: func(conf config.Config, opts ...client.NotificationsOption) (int, *client.NonCacheResp, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				return 200, resp, nil
+			},
+		},
+		&mock.CacheClient{},
+		&mock.NotificationsClient{},
+	)
+
+	backupFile, err := ioutil.TempFile("", "backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(backupFile.Name())
+	ba, _ := defaultBalance(configServerURL, appid, &mock.MetaServerClient{})
+	a, err := NewGoApollo(configServerURL, appid, apolloClient, ba, options.BackupFile(backupFile.Name()))
+	assert.Nil(t, err)
+
+	type settings struct {
+		Timeout time.Duration `apollo:"timeout"`
+		DBHost  string        `apollo:"db.host"`
+		DBPort  int           `apollo:"db.port,default=3306"`
+	}
+
+	var s settings
+	b, err := a.Bind("application", &s)
+	assert.Nil(t, err)
+	assert.Equal(t, settings{Timeout: 100, DBHost: "127.0.0.1", DBPort: 3306}, s)
+	assert.Equal(t, &s, b.Load())
+
+	// 模拟长轮训收到namespace的变更
+	mu.Lock()
+	resp = &client.NonCacheResp{
+		AppID:          appid,
+		NamespaceName:  "application",
+		Configurations: config.Configurations{"timeout": "200", "db.port": "3307"},
+		ReleaseKey:     "2",
+	}
+	mu.Unlock()
+
+	ag := a.(*goApollo)
+	oldValue := ag.getNameSpace("application")
+	_, newValue, err := ag.reloadNamespace(context.Background(), ag.balance, ag.apolloClient, "application")
+	assert.Nil(t, err)
+	ag.sendWatchCh("application", oldValue, newValue)
+
+	// 变更只能通过Load读取，Bind时传入的结构体不再被修改
+	expected := settings{Timeout: 200, DBPort: 3307}
+	assert.Equal(t, settings{Timeout: 100, DBHost: "127.0.0.1", DBPort: 3306}, s)
+	assert.Equal(t, &expected, b.Load())
+
+	// 解除绑定后不再跟随变更
+	b.Unbind()
+	ag.sendWatchCh("application", newValue, config.Configurations{"timeout": "300"})
+	assert.Equal(t, &expected, b.Load())
+
+	_, err = a.Bind("application", s)
+	assert.Equal(t, config.ErrInvalidUnmarshalTarget, err)
+}
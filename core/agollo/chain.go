@@ -0,0 +1,113 @@
+package agollo
+
+import (
+	"sync"
+
+	"github.com/sixgoatsh/agollo/core/config"
+	"github.com/sixgoatsh/agollo/core/options"
+)
+
+// NamespaceChain 按顺序叠加的多个namespace，例如：应用namespace、团队namespace、公共namespace，
+// 同一个key以靠前的namespace为准
+type NamespaceChain struct {
+	a          *goApollo
+	namespaces []string
+}
+
+// Chain 创建namespace链，未初始化的namespace会被加载，加载失败时返回的NamespaceChain仍然可用
+func (a *goApollo) Chain(namespaces ...string) (*NamespaceChain, error) {
+	c := &NamespaceChain{
+		a:          a,
+		namespaces: append([]string(nil), namespaces...),
+	}
+	return c, a.initNamespace(a.ctx, namespaces...)
+}
+
+func (c *NamespaceChain) Namespaces() []string {
+	return append([]string(nil), c.namespaces...)
+}
+
+// Get 与GoApollo.Get一致，在namespace链中查找key
+func (c *NamespaceChain) Get(key string, opts ...options.GetOption) string {
+	return c.a.Get(key, append(opts, options.WithNamespaceChain(c.namespaces...))...)
+}
+
+// Configurations 返回合并后的配置
+func (c *NamespaceChain) Configurations() config.Configurations {
+	conf, _ := c.merge()
+	return conf
+}
+
+// Source 返回提供key的namespace
+func (c *NamespaceChain) Source(key string) (string, bool) {
+	for _, namespace := range c.namespaces {
+		if _, found := c.a.view(namespace)[key]; found {
+			return namespace, true
+		}
+	}
+	return "", false
+}
+
+// Sources 返回合并后每个key来自的namespace
+func (c *NamespaceChain) Sources() map[string]string {
+	_, sources := c.merge()
+	return sources
+}
+
+// AddChangeListener 注册合并后配置的变更回调，被靠前的namespace覆盖的变更不会触发回调，
+// ApolloResponse.Namespace为发生变更的namespace，OldValue及NewValue为合并后的配置
+func (c *NamespaceChain) AddChangeListener(fn func(*ApolloResponse), opts ...options.ListenerOption) (unsubscribe func()) {
+	var (
+		mu       sync.Mutex
+		snapshot = c.Configurations()
+	)
+	handler := func(resp *ApolloResponse) {
+		if resp.Error != nil {
+			fn(resp)
+			return
+		}
+
+		// 每个namespace的监听器在独立的goroutine中回调，这里保证按顺序比较及回调
+		mu.Lock()
+		defer mu.Unlock()
+
+		merged := c.Configurations()
+		changes := snapshot.Different(merged)
+		if len(changes) == 0 {
+			return
+		}
+
+		oldValue := snapshot
+		snapshot = merged
+		fn(&ApolloResponse{
+			Namespace: resp.Namespace,
+			OldValue:  oldValue,
+			NewValue:  merged,
+			Changes:   changes,
+		})
+	}
+
+	var unsubscribes []func()
+	for _, namespace := range c.namespaces {
+		unsubscribes = append(unsubscribes, c.a.AddChangeListener(namespace, handler, opts...))
+	}
+
+	return func() {
+		for _, unsubscribe := range unsubscribes {
+			unsubscribe()
+		}
+	}
+}
+
+func (c *NamespaceChain) merge() (config.Configurations, map[string]string) {
+	conf := config.Configurations{}
+	sources := map[string]string{}
+	// 倒序覆盖，靠前的namespace优先
+	for i := len(c.namespaces) - 1; i >= 0; i-- {
+		for k, v := range c.a.view(c.namespaces[i]) {
+			conf[k] = v
+			sources[k] = c.namespaces[i]
+		}
+	}
+	return conf, sources
+}
@@ -0,0 +1,71 @@
+package agollo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sixgoatsh/agollo/core/apollotest"
+	"github.com/sixgoatsh/agollo/core/config"
+	"github.com/sixgoatsh/agollo/core/options"
+)
+
+func TestNamespaceChain(t *testing.T) {
+	appid := "test"
+	server := apollotest.NewServer(apollotest.Hold(500 * time.Millisecond))
+	defer server.Close()
+	server.Publish(appid, "default", "application", config.Configurations{"timeout": "100"})
+	server.Publish(appid, "default", "team", config.Configurations{"timeout": "200", "retry": "3"})
+	server.Publish(appid, "default", "public", config.Configurations{"retry": "1", "region": "sh"})
+
+	a, err := newTestApollo(t, server.URL,
+		options.NamespaceChain("application", "team", "public"),
+		options.LongPollerInterval(10*time.Millisecond),
+	)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "100", a.Get("timeout"))
+	assert.Equal(t, 3, a.GetInt("retry"))
+	assert.Equal(t, "sh", a.Get("region"))
+	assert.Equal(t, "default", a.Get("missing", options.WithDefault("default")))
+	assert.Equal(t, "200", a.Get("timeout", options.WithNamespace("team")))
+	assert.Equal(t, "1", a.Get("retry", options.WithNamespaceChain("public", "team")))
+
+	chain, err := a.Chain("team", "public")
+	assert.Nil(t, err)
+	assert.Equal(t, "200", chain.Get("timeout"))
+	assert.Equal(t, config.Configurations{"timeout": "200", "retry": "3", "region": "sh"}, chain.Configurations())
+	assert.Equal(t, map[string]string{"timeout": "team", "retry": "team", "region": "public"}, chain.Sources())
+	source, found := chain.Source("region")
+	assert.True(t, found)
+	assert.Equal(t, "public", source)
+
+	responses := make(chan *ApolloResponse, 10)
+	unsubscribe := chain.AddChangeListener(func(resp *ApolloResponse) {
+		responses <- resp
+	})
+	defer unsubscribe()
+
+	errorsCh := a.Start()
+	defer a.Stop()
+
+	// 被team覆盖的变更不触发回调
+	time.Sleep(100 * time.Millisecond)
+	server.Publish(appid, "default", "public", config.Configurations{"retry": "2", "region": "sh"})
+	time.Sleep(200 * time.Millisecond)
+	server.Publish(appid, "default", "public", config.Configurations{"retry": "2", "region": "bj"})
+
+	select {
+	case resp := <-responses:
+		assert.Equal(t, "public", resp.Namespace)
+		assert.Equal(t, config.Changes{config.NewChange(config.ChangeTypeUpdate, "region", "bj")}, resp.Changes)
+		assert.Equal(t, "sh", resp.OldValue["region"])
+		assert.Equal(t, "3", resp.NewValue["retry"])
+	case err := <-errorsCh:
+		t.Fatal(err.Err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("change should reach chain listener")
+	}
+	assert.Empty(t, responses)
+}
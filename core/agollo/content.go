@@ -0,0 +1,89 @@
+package agollo
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/sixgoatsh/agollo/core/config"
+	"github.com/sixgoatsh/agollo/core/util"
+	"github.com/sixgoatsh/agollo/pkg/util/str"
+)
+
+// contentKey 非properties格式的namespace，apollo将配置内容整体保存在content中
+const contentKey = "content"
+
+// namespaceFormat 返回namespace的配置格式，namespace不带后缀时使用Conf.ConfigType
+func (a *goApollo) namespaceFormat(namespace string) config.Format {
+	ext := strings.TrimPrefix(path.Ext(util.GetNamespace(a.opts.Conf.ConfigType, namespace)), ".")
+	return config.Format(ext)
+}
+
+// structured json、yaml格式的namespace可以解析为配置树
+func (a *goApollo) structured(namespace string) bool {
+	switch a.namespaceFormat(namespace) {
+	case config.FormatJSON, config.FormatYAML, config.FormatYML:
+		return true
+	default:
+		return false
+	}
+}
+
+// parsable 开启ParseContent时json、yaml格式的namespace解析为key/value，其他情况与旧版本一致保存在content中
+func (a *goApollo) parsable(namespace string) bool {
+	return a.opts.ParseContent && a.structured(namespace)
+}
+
+// storeConfigurations 解析apollo返回的配置并覆盖缓存，返回解析后的配置
+func (a *goApollo) storeConfigurations(namespace string, raw config.Configurations) (config.Configurations, error) {
+	content, ok := raw[contentKey]
+	if !ok || !a.parsable(namespace) {
+		a.cache.Store(namespace, raw)
+		a.contents.Delete(namespace)
+		return raw, nil
+	}
+
+	s, _ := str.ToStringE(content)
+	conf, err := config.Parse(a.namespaceFormat(namespace), []byte(s))
+	if err != nil {
+		return nil, fmt.Errorf("apollo: parse namespace %s: %w", namespace, err)
+	}
+
+	a.contents.Store(namespace, s)
+	a.cache.Store(namespace, conf)
+	return conf, nil
+}
+
+// rawConfigurations 返回解析前的配置，与apollo返回的格式一致，用于备份及比较
+func (a *goApollo) rawConfigurations(namespace string) (config.Configurations, bool) {
+	if content, ok := a.contents.Load(namespace); ok {
+		return config.Configurations{contentKey: content}, true
+	}
+
+	conf, ok := a.cache.Load(namespace)
+	if !ok {
+		return config.Configurations{}, false
+	}
+	return conf.(config.Configurations), true
+}
+
+// GetContent 返回非properties格式namespace的原始内容，properties格式的namespace返回空字符串
+func (a *goApollo) GetContent(namespace string) string {
+	// 与GetNameSpace一致，开启AutoFetchOnCacheMiss时获取非预加载的namespace
+	a.loadNameSpace(namespace)
+
+	conf, _ := a.rawConfigurations(namespace)
+	content, _ := str.ToStringE(conf[contentKey])
+	return content
+}
+
+// GetTree 返回json、yaml格式namespace结构化的配置树，不需要开启ParseContent，
+// 其他格式的namespace返回与GetNameSpace相同的key/value
+func (a *goApollo) GetTree(namespace string) (map[string]interface{}, error) {
+	conf := a.GetNameSpace(namespace)
+	if !a.structured(namespace) {
+		return map[string]interface{}(conf), nil
+	}
+
+	return config.ParseTree(a.namespaceFormat(namespace), []byte(a.GetContent(namespace)))
+}
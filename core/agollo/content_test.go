@@ -0,0 +1,99 @@
+package agollo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sixgoatsh/agollo/core/apollotest"
+	"github.com/sixgoatsh/agollo/core/backup"
+	"github.com/sixgoatsh/agollo/core/config"
+	"github.com/sixgoatsh/agollo/core/options"
+)
+
+func TestParseContent(t *testing.T) {
+	appid := "test"
+	server := apollotest.NewServer(apollotest.Hold(500 * time.Millisecond))
+	defer server.Close()
+	server.Publish(appid, "default", "datasource.yaml", config.Configurations{
+		"content": "db:\n  host: 127.0.0.1\n  port: 3306\n",
+	})
+	server.Publish(appid, "default", "rules.xml", config.Configurations{"content": "<rules/>"})
+
+	store := backup.NewMemoryStore()
+	a, err := newTestApollo(t, server.URL,
+		options.PreloadNamespaces("datasource.yaml", "rules.xml"),
+		options.ParseContent(),
+		options.WithBackupStore(store),
+		options.LongPollerInterval(10*time.Millisecond),
+	)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "127.0.0.1", a.Get("db.host", options.WithNamespace("datasource.yaml")))
+	assert.Equal(t, 3306, a.GetInt("db.port", options.WithNamespace("datasource.yaml")))
+	assert.Equal(t, "db:\n  host: 127.0.0.1\n  port: 3306\n", a.GetContent("datasource.yaml"))
+	tree, err := a.GetTree("datasource.yaml")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{"host": "127.0.0.1", "port": 3306}, tree["db"])
+
+	// xml、txt格式不解析
+	assert.Equal(t, "<rules/>", a.Get("content", options.WithNamespace("rules.xml")))
+	assert.Equal(t, "<rules/>", a.GetContent("rules.xml"))
+
+	// 备份未解析的内容
+	entry, err := store.Load("datasource.yaml")
+	assert.Nil(t, err)
+	assert.Equal(t, config.Configurations{"content": "db:\n  host: 127.0.0.1\n  port: 3306\n"}, entry.Configurations)
+
+	watchCh := a.WatchNamespace("datasource.yaml", make(chan bool))
+	errorsCh := a.Start()
+	defer a.Stop()
+
+	time.Sleep(100 * time.Millisecond)
+	server.Publish(appid, "default", "datasource.yaml", config.Configurations{
+		"content": "db:\n  host: 127.0.0.1\n  port: 3307\n",
+	})
+
+	select {
+	case resp := <-watchCh:
+		assert.Equal(t, config.Changes{config.NewChange(config.ChangeTypeUpdate, "db.port", "3307")}, resp.Changes)
+	case err := <-errorsCh:
+		t.Fatal(err.Err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("release should reach WatchNamespace() subscribers")
+	}
+
+	// 格式错误时保留旧配置
+	server.Publish(appid, "default", "datasource.yaml", config.Configurations{"content": "db: ["})
+	select {
+	case err := <-errorsCh:
+		assert.Equal(t, "datasource.yaml", err.Namespace)
+	case <-time.After(5 * time.Second):
+		t.Fatal("parse error should reach errors channel")
+	}
+	assert.Equal(t, "3307", a.Get("db.port", options.WithNamespace("datasource.yaml")))
+}
+
+func TestKeepContent(t *testing.T) {
+	appid := "test"
+	server := apollotest.NewServer()
+	defer server.Close()
+	server.Publish(appid, "default", "datasource.json", config.Configurations{"content": `{"db": {"host": "127.0.0.1"}}`})
+
+	a, err := newTestApollo(t, server.URL,
+		options.PreloadNamespaces("datasource.json"),
+	)
+	assert.Nil(t, err)
+	defer a.Stop()
+
+	// 默认与旧版本一致保存在content中
+	assert.Equal(t, config.Configurations{"content": `{"db": {"host": "127.0.0.1"}}`}, a.GetNameSpace("datasource.json"))
+	assert.Equal(t, `{"db": {"host": "127.0.0.1"}}`, a.Get("content", options.WithNamespace("datasource.json")))
+	assert.Equal(t, `{"db": {"host": "127.0.0.1"}}`, a.GetContent("datasource.json"))
+	assert.Equal(t, "", a.Get("db.host", options.WithNamespace("datasource.json")))
+
+	tree, err := a.GetTree("datasource.json")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{"host": "127.0.0.1"}, tree["db"])
+}
@@ -7,4 +7,5 @@ var (
 	defaultConfigType     = "properties"
 	defaultNotificationID = -1
 	defaultWatchTimeout   = 500 * time.Millisecond
+	maxRecentErrors       = 20
 )
@@ -0,0 +1,102 @@
+package agollo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// 按照失败的类型区分的错误，通过errors.Is匹配*NamespaceError、*InitError及*NotReadyError
+var (
+	// ErrNamespaceNotFound apollo中不存在该namespace，或者appID、cluster不存在，HTTP Status: 404
+	ErrNamespaceNotFound = errors.New("apollo: namespace not found")
+	// ErrUnauthorized 访问密钥错误或者未配置，HTTP Status: 401
+	ErrUnauthorized = errors.New("apollo: unauthorized")
+	// ErrServerUnavailable 无法连接ConfigServer或者ConfigServer返回5xx
+	ErrServerUnavailable = errors.New("apollo: server unavailable")
+)
+
+// NamespaceError 加载一个namespace失败的详情
+type NamespaceError struct {
+	Namespace       string
+	Status          int    // apollo返回的HTTP Status，请求失败时为0
+	ConfigServerURL string // 负载均衡选择的ConfigServer，选择失败时为空
+	Err             error
+}
+
+func (e *NamespaceError) Error() string {
+	return fmt.Sprintf("apollo: load namespace %s from %q failed, status: %d: %v", e.Namespace, e.ConfigServerURL, e.Status, e.Err)
+}
+
+func (e *NamespaceError) Unwrap() error {
+	return e.Err
+}
+
+// Is 根据HTTP Status匹配ErrNamespaceNotFound、ErrUnauthorized及ErrServerUnavailable
+func (e *NamespaceError) Is(target error) bool {
+	switch target {
+	case ErrNamespaceNotFound:
+		return e.Status == http.StatusNotFound
+	case ErrUnauthorized:
+		return e.Status == http.StatusUnauthorized
+	case ErrServerUnavailable:
+		if e.Status == 0 {
+			// 停止时中断的请求不是服务端的问题
+			return !errors.Is(e.Err, context.Canceled)
+		}
+		return e.Status >= http.StatusInternalServerError
+	}
+	return false
+}
+
+// InitError 初始化时所有加载失败的namespace，即使部分namespace失败，返回的GoApollo仍然可用
+type InitError struct {
+	Errors []*NamespaceError
+}
+
+func (e *InitError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Sprintf("apollo: %d namespaces failed to initialize: %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+// Is 匹配任意一个namespace的错误，go1.20之前errors.Is不支持Unwrap() []error，因此逐个匹配
+func (e *InitError) Is(target error) bool {
+	for _, err := range e.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As 匹配任意一个namespace的错误
+func (e *InitError) As(target interface{}) bool {
+	for _, err := range e.Errors {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Namespaces 初始化失败的namespace
+func (e *InitError) Namespaces() []string {
+	namespaces := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		namespaces = append(namespaces, err.Namespace)
+	}
+	return namespaces
+}
+
+func newNamespaceError(namespace, configServerURL string, status int, err error) *NamespaceError {
+	var nsErr *NamespaceError
+	if errors.As(err, &nsErr) && nsErr.Namespace == namespace {
+		return nsErr
+	}
+	return &NamespaceError{Namespace: namespace, Status: status, ConfigServerURL: configServerURL, Err: err}
+}
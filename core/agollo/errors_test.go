@@ -0,0 +1,79 @@
+package agollo
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sixgoatsh/agollo/core/apollotest"
+	"github.com/sixgoatsh/agollo/core/client"
+	"github.com/sixgoatsh/agollo/core/config"
+	"github.com/sixgoatsh/agollo/core/options"
+	"github.com/sixgoatsh/agollo/pkg/backoff"
+)
+
+func TestInitError(t *testing.T) {
+	appid := "test"
+	server := apollotest.NewServer(apollotest.Hold(500 * time.Millisecond))
+	defer server.Close()
+	server.Publish(appid, "default", "application", config.Configurations{"timeout": "100"})
+
+	retry := options.WithRetryPolicy(backoff.Policy{MaxAttempts: 1})
+
+	// 返回所有失败的namespace
+	a, err := newTestApollo(t, server.URL, retry, options.PreloadNamespaces("application", "db", "mq"))
+	assert.Equal(t, "100", a.Get("timeout"))
+	a.Stop()
+
+	var initErr *InitError
+	assert.True(t, errors.As(err, &initErr))
+	assert.Equal(t, []string{"db", "mq"}, initErr.Namespaces())
+	assert.Equal(t, http.StatusNotFound, initErr.Errors[0].Status)
+	assert.Equal(t, server.URL, initErr.Errors[0].ConfigServerURL)
+	assert.Contains(t, err.Error(), "namespace mq")
+	assert.True(t, errors.Is(err, ErrNamespaceNotFound))
+	assert.False(t, errors.Is(err, ErrUnauthorized))
+	assert.False(t, errors.Is(err, ErrServerUnavailable))
+	var statusErr *client.StatusError
+	assert.True(t, errors.As(err, &statusErr))
+
+	server.SetAccessKey(appid, "secret")
+	a, err = newTestApollo(t, server.URL, retry, options.PreloadNamespaces("application"))
+	a.Stop()
+	assert.True(t, errors.Is(err, ErrUnauthorized))
+
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	a, err = newTestApollo(t, down.URL, retry, options.PreloadNamespaces("application"))
+	a.Stop()
+	assert.True(t, errors.Is(err, ErrServerUnavailable))
+	assert.True(t, errors.As(err, &initErr))
+	assert.Equal(t, http.StatusServiceUnavailable, initErr.Errors[0].Status)
+
+	// 无法连接时同样是ErrServerUnavailable
+	down.Close()
+	a, err = newTestApollo(t, down.URL, retry, options.PreloadNamespaces("application"))
+	a.Stop()
+	assert.True(t, errors.Is(err, ErrServerUnavailable))
+	assert.True(t, errors.As(err, &initErr))
+	assert.Equal(t, 0, initErr.Errors[0].Status)
+
+	// 未就绪的错误同样可以区分失败的类型
+	a, err = newTestApollo(t, server.URL, retry, options.RequiredNamespaces("application"), options.AccessKey("secret"))
+	a.Stop()
+	assert.Nil(t, err)
+	a, err = newTestApollo(t, server.URL, retry, options.RequiredNamespaces("application"), options.PreloadNamespaces("db"))
+	a.Stop()
+	var notReady *NotReadyError
+	assert.True(t, errors.As(err, &notReady))
+	assert.True(t, errors.Is(err, ErrUnauthorized))
+	// 初始化的错误同样保留，包含未被要求就绪的namespace
+	assert.True(t, errors.As(err, &initErr))
+	assert.Equal(t, []string{"db", "application"}, initErr.Namespaces())
+	assert.Equal(t, initErr, notReady.Init)
+}
@@ -0,0 +1,217 @@
+package agollo
+
+import (
+	"time"
+
+	"github.com/sixgoatsh/agollo/core/options"
+	"github.com/sixgoatsh/agollo/pkg/util/str"
+)
+
+// lookup 读取key对应的原始值，key不存在时返回WithDefault设置的默认值
+func (a *goApollo) lookup(key string, opts []options.GetOption) (interface{}, options.GetOptions) {
+	getOpts := a.opts.NewGetOptions(opts...)
+
+	if getOpts.AppID != a.opts.Conf.AppID || getOpts.Cluster != a.opts.Conf.ClusterName {
+		sub, err := a.root().subscribe(a.ctx, getOpts.AppID, getOpts.Cluster)
+		if err != nil {
+			a.logger().Error("subscribe failed", "subscribe_app_id", getOpts.AppID, "subscribe_cluster", getOpts.Cluster, "error", err)
+		}
+		return sub.lookup(key, opts)
+	}
+
+	if len(getOpts.Namespaces) > 0 {
+		for _, namespace := range getOpts.Namespaces {
+			if val, found := a.loadNameSpace(namespace)[key]; found {
+				return a.interpolateValue(namespace, key, val), getOpts
+			}
+		}
+		return defaultValue(getOpts), getOpts
+	}
+
+	val, found := a.loadNameSpace(getOpts.Namespace)[key]
+	if !found {
+		return defaultValue(getOpts), getOpts
+	}
+
+	return a.interpolateValue(getOpts.Namespace, key, val), getOpts
+}
+
+// defaultValue 未设置默认值时返回nil，转换后得到对应类型的零值
+func defaultValue(getOpts options.GetOptions) interface{} {
+	if getOpts.DefaultValue == "" {
+		return nil
+	}
+	return getOpts.DefaultValue
+}
+
+func (a *goApollo) GetIntE(key string, opts ...options.GetOption) (int, error) {
+	val, _ := a.lookup(key, opts)
+	return str.ToIntE(val)
+}
+
+// GetInt 配置值无法转换时返回默认值，不存在默认值时返回0
+func (a *goApollo) GetInt(key string, opts ...options.GetOption) int {
+	v, err := a.GetIntE(key, opts...)
+	if err != nil {
+		v, _ = str.ToIntE(defaultValue(a.opts.NewGetOptions(opts...)))
+	}
+	return v
+}
+
+func (a *goApollo) GetInt64E(key string, opts ...options.GetOption) (int64, error) {
+	val, _ := a.lookup(key, opts)
+	return str.ToInt64E(val)
+}
+
+func (a *goApollo) GetInt64(key string, opts ...options.GetOption) int64 {
+	v, err := a.GetInt64E(key, opts...)
+	if err != nil {
+		v, _ = str.ToInt64E(defaultValue(a.opts.NewGetOptions(opts...)))
+	}
+	return v
+}
+
+func (a *goApollo) GetBoolE(key string, opts ...options.GetOption) (bool, error) {
+	val, _ := a.lookup(key, opts)
+	return str.ToBoolE(val)
+}
+
+func (a *goApollo) GetBool(key string, opts ...options.GetOption) bool {
+	v, err := a.GetBoolE(key, opts...)
+	if err != nil {
+		v, _ = str.ToBoolE(defaultValue(a.opts.NewGetOptions(opts...)))
+	}
+	return v
+}
+
+func (a *goApollo) GetFloat64E(key string, opts ...options.GetOption) (float64, error) {
+	val, _ := a.lookup(key, opts)
+	return str.ToFloat64E(val)
+}
+
+func (a *goApollo) GetFloat64(key string, opts ...options.GetOption) float64 {
+	v, err := a.GetFloat64E(key, opts...)
+	if err != nil {
+		v, _ = str.ToFloat64E(defaultValue(a.opts.NewGetOptions(opts...)))
+	}
+	return v
+}
+
+func (a *goApollo) GetDurationE(key string, opts ...options.GetOption) (time.Duration, error) {
+	val, _ := a.lookup(key, opts)
+	return str.ToDurationE(val)
+}
+
+func (a *goApollo) GetDuration(key string, opts ...options.GetOption) time.Duration {
+	v, err := a.GetDurationE(key, opts...)
+	if err != nil {
+		v, _ = str.ToDurationE(defaultValue(a.opts.NewGetOptions(opts...)))
+	}
+	return v
+}
+
+func (a *goApollo) GetTimeE(key string, opts ...options.GetOption) (time.Time, error) {
+	val, _ := a.lookup(key, opts)
+	return str.ToTimeE(val)
+}
+
+func (a *goApollo) GetTime(key string, opts ...options.GetOption) time.Time {
+	v, err := a.GetTimeE(key, opts...)
+	if err != nil {
+		v, _ = str.ToTimeE(defaultValue(a.opts.NewGetOptions(opts...)))
+	}
+	return v
+}
+
+// GetStringSliceE 按照WithSeparator设置的分隔符切割配置值，默认分隔符为","
+func (a *goApollo) GetStringSliceE(key string, opts ...options.GetOption) ([]string, error) {
+	val, getOpts := a.lookup(key, opts)
+	return str.ToStringSliceE(val, getOpts.Separator)
+}
+
+func (a *goApollo) GetStringSlice(key string, opts ...options.GetOption) []string {
+	v, err := a.GetStringSliceE(key, opts...)
+	if err != nil {
+		getOpts := a.opts.NewGetOptions(opts...)
+		v, _ = str.ToStringSliceE(defaultValue(getOpts), getOpts.Separator)
+	}
+	return v
+}
+
+// GetStringMapE 配置值为字符串时按照JSON对象解析
+func (a *goApollo) GetStringMapE(key string, opts ...options.GetOption) (map[string]interface{}, error) {
+	val, _ := a.lookup(key, opts)
+	return str.ToStringMapE(val)
+}
+
+func (a *goApollo) GetStringMap(key string, opts ...options.GetOption) map[string]interface{} {
+	v, err := a.GetStringMapE(key, opts...)
+	if err != nil {
+		v, _ = str.ToStringMapE(defaultValue(a.opts.NewGetOptions(opts...)))
+	}
+	return v
+}
+
+func GetInt(key string, opts ...options.GetOption) int {
+	return defaultGoApollo.GetInt(key, opts...)
+}
+
+func GetIntE(key string, opts ...options.GetOption) (int, error) {
+	return defaultGoApollo.GetIntE(key, opts...)
+}
+
+func GetInt64(key string, opts ...options.GetOption) int64 {
+	return defaultGoApollo.GetInt64(key, opts...)
+}
+
+func GetInt64E(key string, opts ...options.GetOption) (int64, error) {
+	return defaultGoApollo.GetInt64E(key, opts...)
+}
+
+func GetBool(key string, opts ...options.GetOption) bool {
+	return defaultGoApollo.GetBool(key, opts...)
+}
+
+func GetBoolE(key string, opts ...options.GetOption) (bool, error) {
+	return defaultGoApollo.GetBoolE(key, opts...)
+}
+
+func GetFloat64(key string, opts ...options.GetOption) float64 {
+	return defaultGoApollo.GetFloat64(key, opts...)
+}
+
+func GetFloat64E(key string, opts ...options.GetOption) (float64, error) {
+	return defaultGoApollo.GetFloat64E(key, opts...)
+}
+
+func GetDuration(key string, opts ...options.GetOption) time.Duration {
+	return defaultGoApollo.GetDuration(key, opts...)
+}
+
+func GetDurationE(key string, opts ...options.GetOption) (time.Duration, error) {
+	return defaultGoApollo.GetDurationE(key, opts...)
+}
+
+func GetTime(key string, opts ...options.GetOption) time.Time {
+	return defaultGoApollo.GetTime(key, opts...)
+}
+
+func GetTimeE(key string, opts ...options.GetOption) (time.Time, error) {
+	return defaultGoApollo.GetTimeE(key, opts...)
+}
+
+func GetStringSlice(key string, opts ...options.GetOption) []string {
+	return defaultGoApollo.GetStringSlice(key, opts...)
+}
+
+func GetStringSliceE(key string, opts ...options.GetOption) ([]string, error) {
+	return defaultGoApollo.GetStringSliceE(key, opts...)
+}
+
+func GetStringMap(key string, opts ...options.GetOption) map[string]interface{} {
+	return defaultGoApollo.GetStringMap(key, opts...)
+}
+
+func GetStringMapE(key string, opts ...options.GetOption) (map[string]interface{}, error) {
+	return defaultGoApollo.GetStringMapE(key, opts...)
+}
@@ -0,0 +1,216 @@
+package agollo
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sixgoatsh/agollo/core/config"
+)
+
+const (
+	// envPrefix ${ENV:HOME}读取环境变量
+	envPrefix = "ENV:"
+	// defaultSep ${key:-fallback}中默认值的分隔符
+	defaultSep = ":-"
+)
+
+// resolver 解析配置值中的占位符：
+//   - ${key}：同一namespace中的key
+//   - ${namespace:key}：其他namespace中的key
+//   - ${ENV:NAME}：环境变量
+//   - ${key:-fallback}：key不存在时使用fallback，fallback中也可以包含占位符
+//   - $${key}：不解析，输出${key}
+//
+// 无法解析的占位符保留原样，并返回第一个遇到的错误
+type resolver struct {
+	load     func(namespace string) config.Configurations
+	visiting map[string]bool // key: namespace:key 正在解析的key，用于检测循环引用
+}
+
+func newResolver(load func(namespace string) config.Configurations) *resolver {
+	return &resolver{
+		load:     load,
+		visiting: map[string]bool{},
+	}
+}
+
+// resolveNamespace 返回解析占位符后的配置，不修改conf
+func (r *resolver) resolveNamespace(namespace string, conf config.Configurations) (config.Configurations, error) {
+	var firstErr error
+	resolved := make(config.Configurations, len(conf))
+	for key, val := range conf {
+		v, err := r.resolveKey(namespace, key, val)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		resolved[key] = v
+	}
+	return resolved, firstErr
+}
+
+// resolveKey 解析namespace中key的值，非字符串的值原样返回
+func (r *resolver) resolveKey(namespace, key string, val interface{}) (interface{}, error) {
+	s, ok := val.(string)
+	if !ok || !strings.Contains(s, "${") {
+		return val, nil
+	}
+
+	id := namespace + ":" + key
+	if r.visiting[id] {
+		return s, fmt.Errorf("apollo: circular placeholder reference: %s", id)
+	}
+	r.visiting[id] = true
+	defer delete(r.visiting, id)
+
+	return r.resolveString(namespace, s)
+}
+
+func (r *resolver) resolveString(namespace, s string) (string, error) {
+	var (
+		b        strings.Builder
+		firstErr error
+	)
+	for {
+		start := strings.Index(s, "${")
+		if start < 0 {
+			b.WriteString(s)
+			break
+		}
+		if start > 0 && s[start-1] == '$' {
+			b.WriteString(s[:start-1])
+			b.WriteString("${")
+			s = s[start+2:]
+			continue
+		}
+
+		end := closingBrace(s, start+2)
+		if end < 0 {
+			b.WriteString(s)
+			break
+		}
+
+		b.WriteString(s[:start])
+		v, err := r.resolveExpr(namespace, s[start+2:end])
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		b.WriteString(v)
+		s = s[end+1:]
+	}
+	return b.String(), firstErr
+}
+
+func (r *resolver) resolveExpr(namespace, expr string) (string, error) {
+	ref, fallback, hasFallback := expr, "", false
+	if i := strings.Index(expr, defaultSep); i >= 0 {
+		ref, fallback, hasFallback = expr[:i], expr[i+len(defaultSep):], true
+	}
+
+	var (
+		val   interface{}
+		found bool
+		err   error
+	)
+	switch {
+	case strings.HasPrefix(ref, envPrefix):
+		val, found = os.LookupEnv(strings.TrimPrefix(ref, envPrefix))
+	case strings.Contains(ref, ":"):
+		i := strings.Index(ref, ":")
+		refNamespace, key := ref[:i], ref[i+1:]
+		if val, found = r.load(refNamespace)[key]; found {
+			val, err = r.resolveKey(refNamespace, key, val)
+		}
+	default:
+		if val, found = r.load(namespace)[ref]; found {
+			val, err = r.resolveKey(namespace, ref, val)
+		}
+	}
+
+	if err != nil {
+		return "${" + expr + "}", err
+	}
+	if !found {
+		if hasFallback {
+			return r.resolveString(namespace, fallback)
+		}
+		return "${" + expr + "}", fmt.Errorf("apollo: unresolved placeholder ${%s} in namespace %s", expr, namespace)
+	}
+	return fmt.Sprint(val), nil
+}
+
+// closingBrace 返回与占位符匹配的"}"的位置，支持默认值中嵌套占位符
+func closingBrace(s string, from int) int {
+	depth := 1
+	for i := from; i < len(s); i++ {
+		switch {
+		case s[i] == '{' && i > 0 && s[i-1] == '$':
+			depth++
+		case s[i] == '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// interpolate 开启Interpolate时返回解析占位符后的配置，无法解析的占位符保留原样
+func (a *goApollo) interpolate(namespace string, conf config.Configurations) config.Configurations {
+	if !a.opts.Interpolate {
+		return conf
+	}
+	resolved, _ := newResolver(a.loadNameSpace).resolveNamespace(namespace, conf)
+	return resolved
+}
+
+// interpolateValue 开启Interpolate时解析单个key的值
+func (a *goApollo) interpolateValue(namespace, key string, val interface{}) interface{} {
+	if !a.opts.Interpolate {
+		return val
+	}
+	resolved, _ := newResolver(a.loadNameSpace).resolveKey(namespace, key, val)
+	return resolved
+}
+
+// view 返回已加载的namespace叠加覆盖配置并解析占位符后的配置，不会触发AutoFetchOnCacheMiss
+func (a *goApollo) view(namespace string) config.Configurations {
+	return a.interpolate(namespace, a.effective(namespace))
+}
+
+// sendInterpolatedWatchCh namespace变更后，分别发送该namespace及引用了该namespace的其他namespace解析后的变更，
+// 例如：b中的 url=${a:host}，a中的host变更时b也会收到url的变更
+func (a *goApollo) sendInterpolatedWatchCh(namespace string, oldVal, newVal config.Configurations) {
+	before := newResolver(func(ns string) config.Configurations {
+		if ns == namespace {
+			return oldVal
+		}
+		return a.effective(ns)
+	})
+	after := newResolver(func(ns string) config.Configurations {
+		if ns == namespace {
+			return newVal
+		}
+		return a.effective(ns)
+	})
+
+	resolve := func(ns string, oldConf, newConf config.Configurations) {
+		oldResolved, _ := before.resolveNamespace(ns, oldConf)
+		newResolved, err := after.resolveNamespace(ns, newConf)
+		if err != nil {
+			a.logger().Warn("interpolate namespace failed", "namespace", ns, "error", err)
+		}
+		a.emitWatchCh(ns, oldResolved, newResolved)
+	}
+
+	resolve(namespace, oldVal, newVal)
+
+	a.initialized.Range(func(key, _ interface{}) bool {
+		if ns := key.(string); ns != namespace {
+			conf := a.effective(ns)
+			resolve(ns, conf, conf)
+		}
+		return true
+	})
+}
@@ -0,0 +1,102 @@
+package agollo
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sixgoatsh/agollo/core/apollotest"
+	"github.com/sixgoatsh/agollo/core/config"
+	"github.com/sixgoatsh/agollo/core/options"
+)
+
+func TestResolver(t *testing.T) {
+	os.Setenv("AGOLLO_TEST_HOME", "/home/agollo")
+	defer os.Unsetenv("AGOLLO_TEST_HOME")
+
+	namespaces := map[string]config.Configurations{
+		"application": {
+			"host":     "127.0.0.1",
+			"port":     "3306",
+			"addr":     "${host}:${port}",
+			"dsn":      "mysql://${addr}/${common:db}",
+			"home":     "${ENV:AGOLLO_TEST_HOME}/conf",
+			"fallback": "${missing:-${host}}",
+			"empty":    "${missing:-}",
+			"escaped":  "$${host}",
+			"loop1":    "${loop2}",
+			"loop2":    "${loop1}",
+			"unknown":  "${missing}",
+			"int":      1,
+		},
+		"common": {
+			"db": "test",
+		},
+	}
+	r := newResolver(func(namespace string) config.Configurations {
+		return namespaces[namespace]
+	})
+
+	var tests = []struct {
+		Key      string
+		Expected interface{}
+		Err      bool
+	}{
+		{"addr", "127.0.0.1:3306", false},
+		{"dsn", "mysql://127.0.0.1:3306/test", false},
+		{"home", "/home/agollo/conf", false},
+		{"fallback", "127.0.0.1", false},
+		{"empty", "", false},
+		{"escaped", "${host}", false},
+		{"loop1", "${loop2}", true},
+		{"unknown", "${missing}", true},
+		{"int", 1, false},
+	}
+	for _, test := range tests {
+		actual, err := r.resolveKey("application", test.Key, namespaces["application"][test.Key])
+		assert.Equal(t, test.Expected, actual, test.Key)
+		assert.Equal(t, test.Err, err != nil, test.Key)
+	}
+}
+
+func TestInterpolate(t *testing.T) {
+	appid := "test"
+	server := apollotest.NewServer(apollotest.Hold(500 * time.Millisecond))
+	defer server.Close()
+	server.Publish(appid, "default", "application", config.Configurations{
+		"host": "127.0.0.1",
+		"addr": "${host}:${common:port}",
+	})
+	server.Publish(appid, "default", "common", config.Configurations{"port": "3306"})
+
+	a, err := newTestApollo(t, server.URL,
+		options.PreloadNamespaces("application", "common"),
+		options.LongPollerInterval(10*time.Millisecond),
+		options.Interpolate(),
+	)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "127.0.0.1:3306", a.Get("addr"))
+	assert.Equal(t, "127.0.0.1:3306", a.GetNameSpace("application")["addr"])
+
+	watchCh := a.WatchNamespace("application", make(chan bool))
+	errorsCh := a.Start()
+	defer a.Stop()
+
+	// 引用的key变更时，引用方同样收到变更
+	time.Sleep(100 * time.Millisecond)
+	server.Publish(appid, "default", "common", config.Configurations{"port": "3307"})
+
+	select {
+	case resp := <-watchCh:
+		assert.Equal(t, "application", resp.Namespace)
+		assert.Equal(t, config.Changes{config.NewChange(config.ChangeTypeUpdate, "addr", "127.0.0.1:3307")}, resp.Changes)
+	case err := <-errorsCh:
+		t.Fatal(err.Err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("dependent change should reach WatchNamespace() subscribers")
+	}
+	assert.Equal(t, "127.0.0.1:3307", a.Get("addr"))
+}
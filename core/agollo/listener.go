@@ -0,0 +1,245 @@
+package agollo
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/sixgoatsh/agollo/core/config"
+	"github.com/sixgoatsh/agollo/core/options"
+)
+
+// listener 每个监听器拥有独立的goroutine和有界队列，消费较慢的监听器不会影响其他监听器
+type listener struct {
+	namespace string // 为空时监听所有namespace
+	keyPrefix string // 非空时仅在前缀匹配的key变更时触发
+	fn        func(*ApolloResponse)
+	opts      options.ListenerOptions
+
+	mu     sync.Mutex
+	cond   *sync.Cond
+	queue  []*ApolloResponse
+	closed bool
+}
+
+func newListener(namespace, keyPrefix string, fn func(*ApolloResponse), opts options.ListenerOptions) *listener {
+	l := &listener{
+		namespace: namespace,
+		keyPrefix: keyPrefix,
+		fn:        fn,
+		opts:      opts,
+	}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+func (l *listener) match(namespace string) bool {
+	return l.namespace == "" || l.namespace == fixWatchNamespace(namespace)
+}
+
+// filter 按照key前缀过滤变更，没有匹配的变更时返回nil
+func (l *listener) filter(resp *ApolloResponse) *ApolloResponse {
+	if l.keyPrefix == "" || resp.Error != nil {
+		return resp
+	}
+
+	var changes config.Changes
+	for _, change := range resp.Changes {
+		if strings.HasPrefix(change.Key, l.keyPrefix) {
+			changes = append(changes, change)
+		}
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	filtered := *resp
+	filtered.Changes = changes
+	return &filtered
+}
+
+func (l *listener) push(resp *ApolloResponse) {
+	resp = l.filter(resp)
+	if resp == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for len(l.queue) >= l.opts.QueueSize && !l.closed {
+		switch l.opts.OverflowPolicy {
+		case options.OverflowBlock:
+			l.cond.Wait()
+			continue
+		case options.OverflowCoalesce:
+			// 仅在队列满时合并，队列未满时每个事件都会送达
+			if l.coalesce(resp) {
+				return
+			}
+		}
+		l.queue = l.queue[1:]
+	}
+	if l.closed {
+		return
+	}
+
+	l.queue = append(l.queue, resp)
+	l.cond.Broadcast()
+}
+
+// coalesce 将事件合并到队列中同一个namespace未被消费的事件，没有可以合并的事件时返回false
+func (l *listener) coalesce(resp *ApolloResponse) bool {
+	if resp.Error != nil {
+		return false
+	}
+
+	for i, pending := range l.queue {
+		if pending.Namespace != resp.Namespace || pending.AppID != resp.AppID || pending.Cluster != resp.Cluster ||
+			pending.Error != nil {
+			continue
+		}
+
+		merged := &ApolloResponse{
+			AppID:     resp.AppID,
+			Cluster:   resp.Cluster,
+			Namespace: resp.Namespace,
+			OldValue:  pending.OldValue,
+			NewValue:  resp.NewValue,
+			Changes:   pending.OldValue.Different(resp.NewValue),
+		}
+		if merged = l.filter(merged); merged == nil || len(merged.Changes) == 0 {
+			// 变更被抵消，例如先修改再改回原值
+			l.queue = append(l.queue[:i], l.queue[i+1:]...)
+		} else {
+			l.queue[i] = merged
+		}
+		return true
+	}
+	return false
+}
+
+func (l *listener) run(recoverFn func(interface{})) {
+	for {
+		l.mu.Lock()
+		for len(l.queue) == 0 && !l.closed {
+			l.cond.Wait()
+		}
+		if l.closed {
+			l.mu.Unlock()
+			return
+		}
+		resp := l.queue[0]
+		l.queue = l.queue[1:]
+		l.cond.Broadcast()
+		l.mu.Unlock()
+
+		l.call(resp, recoverFn)
+	}
+}
+
+func (l *listener) call(resp *ApolloResponse, recoverFn func(interface{})) {
+	defer func() {
+		if r := recover(); r != nil {
+			recoverFn(r)
+		}
+	}()
+	l.fn(resp)
+}
+
+func (l *listener) close() {
+	l.mu.Lock()
+	l.closed = true
+	l.queue = nil
+	l.cond.Broadcast()
+	l.mu.Unlock()
+}
+
+// AddChangeListener 注册namespace的变更回调，namespace为空时监听所有namespace
+// 每个监听器在独立的goroutine中按顺序回调，队列满时按照ListenerOverflowPolicy处理
+// 返回的函数用于取消监听，未被回调的事件会被丢弃
+func (a *goApollo) AddChangeListener(namespace string, fn func(*ApolloResponse), opts ...options.ListenerOption) (unsubscribe func()) {
+	return a.addListener(namespace, "", fn, opts...)
+}
+
+// AddKeyListener 注册namespace下指定key前缀的变更回调，ApolloResponse.Changes仅包含前缀匹配的变更
+func (a *goApollo) AddKeyListener(namespace, keyPrefix string, fn func(*ApolloResponse), opts ...options.ListenerOption) (unsubscribe func()) {
+	return a.addListener(namespace, keyPrefix, fn, opts...)
+}
+
+func (a *goApollo) addListener(namespace, keyPrefix string, fn func(*ApolloResponse), opts ...options.ListenerOption) func() {
+	watchNamespace := namespace
+	if namespace != "" {
+		watchNamespace = fixWatchNamespace(namespace)
+	}
+
+	l := newListener(watchNamespace, keyPrefix, fn, a.opts.NewListenerOptions(opts...))
+	go l.run(func(r interface{}) {
+		a.logger().Error("change listener panicked", "namespace", namespace, "panic", r)
+	})
+
+	a.listenersLock.Lock()
+	a.listeners = append(a.listeners, l)
+	a.listenersLock.Unlock()
+
+	if namespace != "" {
+		go func() {
+			// 非预加载以外的namespace,初始化基础meta信息,否则没有longpoll
+			if err := a.initNamespace(a.ctx, namespace); err != nil {
+				l.push(&ApolloResponse{
+					Namespace: namespace,
+					Error:     err,
+				})
+			}
+		}()
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			a.removeListener(l)
+			l.close()
+		})
+	}
+}
+
+func (a *goApollo) removeListener(l *listener) {
+	a.listenersLock.Lock()
+	defer a.listenersLock.Unlock()
+	for i := range a.listeners {
+		if a.listeners[i] == l {
+			a.listeners = append(a.listeners[:i:i], a.listeners[i+1:]...)
+			return
+		}
+	}
+}
+
+func (a *goApollo) sendListeners(resp *ApolloResponse) {
+	a.listenersLock.RLock()
+	ls := a.listeners
+	a.listenersLock.RUnlock()
+
+	for _, l := range ls {
+		if l.match(resp.Namespace) {
+			l.push(resp)
+		}
+	}
+}
+
+func (a *goApollo) closeListeners() {
+	a.listenersLock.Lock()
+	ls := a.listeners
+	a.listeners = nil
+	a.listenersLock.Unlock()
+
+	for _, l := range ls {
+		l.close()
+	}
+}
+
+func AddChangeListener(namespace string, fn func(*ApolloResponse), opts ...options.ListenerOption) func() {
+	return defaultGoApollo.AddChangeListener(namespace, fn, opts...)
+}
+
+func AddKeyListener(namespace, keyPrefix string, fn func(*ApolloResponse), opts ...options.ListenerOption) func() {
+	return defaultGoApollo.AddKeyListener(namespace, keyPrefix, fn, opts...)
+}
@@ -0,0 +1,118 @@
+package agollo
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sixgoatsh/agollo/core/config"
+	"github.com/sixgoatsh/agollo/core/options"
+)
+
+func TestChangeListener(t *testing.T) {
+	opts, err := options.NewOptions("http://localhost:8080", "test")
+	assert.Nil(t, err)
+	a := &goApollo{opts: opts, bindings: map[string][]*Binding{}, ctx: context.Background()}
+	a.initialized.Store("application", true)
+	defer a.closeListeners()
+
+	collect := func(n int) (func(*ApolloResponse), func() []*ApolloResponse) {
+		var (
+			mu    sync.Mutex
+			resps []*ApolloResponse
+			wg    sync.WaitGroup
+		)
+		wg.Add(n)
+		return func(resp *ApolloResponse) {
+				mu.Lock()
+				resps = append(resps, resp)
+				mu.Unlock()
+				wg.Done()
+			}, func() []*ApolloResponse {
+				wg.Wait()
+				mu.Lock()
+				defer mu.Unlock()
+				return resps
+			}
+	}
+
+	// 阻塞策略下所有事件按顺序到达
+	fn, wait := collect(3)
+	unsubscribe := a.AddChangeListener("application", fn, options.WithOverflowPolicy(options.OverflowBlock))
+	a.sendWatchCh("application", config.Configurations{}, config.Configurations{"timeout": "1"})
+	a.sendWatchCh("application", config.Configurations{"timeout": "1"}, config.Configurations{"timeout": "2"})
+	a.sendWatchCh("other", config.Configurations{}, config.Configurations{"timeout": "1"})
+	a.sendWatchCh("application", config.Configurations{"timeout": "2"}, config.Configurations{"timeout": "3"})
+	resps := wait()
+	assert.Equal(t, 3, len(resps))
+	for i, resp := range resps {
+		assert.Equal(t, fmt.Sprint(i+1), resp.NewValue["timeout"])
+	}
+	unsubscribe()
+
+	// 合并策略下队列未满时不合并，所有事件都会送达
+	block := make(chan struct{})
+	fn, wait = collect(2)
+	unsubscribe = a.AddChangeListener("application", func(resp *ApolloResponse) {
+		<-block
+		fn(resp)
+	}, options.WithOverflowPolicy(options.OverflowCoalesce))
+	a.sendWatchCh("application", config.Configurations{}, config.Configurations{"timeout": "1"})
+	a.sendWatchCh("application", config.Configurations{"timeout": "1"}, config.Configurations{"timeout": "2"})
+	close(block)
+	resps = wait()
+	assert.Equal(t, 2, len(resps))
+	assert.Equal(t, "1", resps[0].NewValue["timeout"])
+	assert.Equal(t, "2", resps[1].NewValue["timeout"])
+	unsubscribe()
+
+	// 合并策略下队列满时未被消费的事件合并为一个
+	block = make(chan struct{})
+	fn, wait = collect(2)
+	unsubscribe = a.AddChangeListener("application", func(resp *ApolloResponse) {
+		<-block
+		fn(resp)
+	}, options.WithQueueSize(1), options.WithOverflowPolicy(options.OverflowCoalesce))
+	a.sendWatchCh("application", config.Configurations{}, config.Configurations{"timeout": "1"})
+	time.Sleep(100 * time.Millisecond) // 等待第一个事件被取出
+	a.sendWatchCh("application", config.Configurations{"timeout": "1"}, config.Configurations{"timeout": "2"})
+	a.sendWatchCh("application", config.Configurations{"timeout": "2"}, config.Configurations{"timeout": "3", "retry": "1"})
+	close(block)
+	resps = wait()
+	assert.Equal(t, config.Configurations{"timeout": "1"}, resps[1].OldValue)
+	assert.Equal(t, config.Configurations{"timeout": "3", "retry": "1"}, resps[1].NewValue)
+	assert.Equal(t, 2, len(resps[1].Changes))
+	assert.Equal(t, "test", resps[1].AppID)
+	assert.Equal(t, "default", resps[1].Cluster)
+	unsubscribe()
+
+	// 丢弃最早事件策略下，队列满时保留最新的事件
+	block = make(chan struct{})
+	fn, wait = collect(2)
+	unsubscribe = a.AddChangeListener("", func(resp *ApolloResponse) {
+		<-block
+		fn(resp)
+	}, options.WithQueueSize(1), options.WithOverflowPolicy(options.OverflowDropOldest))
+	a.sendWatchCh("application", config.Configurations{}, config.Configurations{"timeout": "1"})
+	time.Sleep(100 * time.Millisecond)
+	a.sendWatchCh("application", config.Configurations{"timeout": "1"}, config.Configurations{"timeout": "2"})
+	a.sendWatchCh("other", config.Configurations{}, config.Configurations{"timeout": "3"})
+	close(block)
+	resps = wait()
+	assert.Equal(t, "1", resps[0].NewValue["timeout"])
+	assert.Equal(t, "other", resps[1].Namespace)
+	unsubscribe()
+
+	// key前缀监听仅在匹配的key变更时触发
+	fn, wait = collect(1)
+	unsubscribe = a.AddKeyListener("application", "db.", fn)
+	a.sendWatchCh("application", config.Configurations{}, config.Configurations{"timeout": "1"})
+	a.sendWatchCh("application", config.Configurations{"timeout": "1"}, config.Configurations{"timeout": "2", "db.host": "localhost"})
+	resps = wait()
+	assert.Equal(t, config.Changes{config.NewChange(config.ChangeTypeAdd, "db.host", "localhost")}, resps[0].Changes)
+	unsubscribe()
+}
@@ -0,0 +1,124 @@
+package agollo
+
+import (
+	"flag"
+	"sort"
+	"strings"
+
+	"github.com/sixgoatsh/agollo/core/config"
+)
+
+const (
+	overrideSourceOptions = "options"
+	overrideSourceEnv     = "env:"
+	overrideSourceFlag    = "flag:-"
+)
+
+// Override 覆盖apollo配置的值，用于紧急情况下在单个实例上修改配置，无需在apollo发布
+type Override struct {
+	Namespace string // 为空时覆盖所有namespace中已存在的key，非空时仅覆盖该namespace，key不存在时新增
+	Key       string
+	Value     string
+	Source    string // 覆盖配置的来源，例如：options、env:APOLLO_OVERRIDE_DB_HOST、flag:-db.host
+}
+
+// Overrides 返回当前生效的覆盖配置，同一namespace及key只返回优先级最高的一项
+func (a *goApollo) Overrides() []Override {
+	active := map[[2]string]Override{}
+	for _, o := range a.overrides() {
+		active[[2]string{o.Namespace, o.Key}] = o
+	}
+
+	list := make([]Override, 0, len(active))
+	for _, o := range active {
+		list = append(list, o)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Namespace != list[j].Namespace {
+			return list[i].Namespace < list[j].Namespace
+		}
+		return list[i].Key < list[j].Key
+	})
+	return list
+}
+
+// overrides 按优先级从低到高返回所有覆盖配置：options < 环境变量 < 命令行参数
+func (a *goApollo) overrides() []Override {
+	var list []Override
+	for namespace, conf := range a.opts.Overrides {
+		for key, value := range conf {
+			list = append(list, Override{Namespace: namespace, Key: key, Value: value, Source: overrideSourceOptions})
+		}
+	}
+
+	list = append(list, a.envOverrides...)
+
+	// 命令行参数可能在创建GoApollo之后才解析，每次读取，仅使用显式设置的参数
+	if fs := a.opts.OverrideFlags; fs != nil && fs.Parsed() {
+		fs.Visit(func(f *flag.Flag) {
+			list = append(list, Override{Key: f.Name, Value: f.Value.String(), Source: overrideSourceFlag + f.Name})
+		})
+	}
+	return list
+}
+
+// applyOverrides 返回叠加覆盖配置后的配置，没有需要覆盖的key时返回conf本身
+func (a *goApollo) applyOverrides(namespace string, conf config.Configurations) config.Configurations {
+	var result config.Configurations
+	for _, o := range a.overrides() {
+		if o.Namespace != "" && fixWatchNamespace(o.Namespace) != fixWatchNamespace(namespace) {
+			continue
+		}
+		if _, found := conf[o.Key]; o.Namespace == "" && !found {
+			continue
+		}
+
+		if result == nil {
+			result = make(config.Configurations, len(conf)+1)
+			for k, v := range conf {
+				result[k] = v
+			}
+		}
+		result[o.Key] = o.Value
+	}
+
+	if result == nil {
+		return conf
+	}
+	return result
+}
+
+// effective 返回已加载的namespace叠加覆盖配置后的配置
+func (a *goApollo) effective(namespace string) config.Configurations {
+	return a.applyOverrides(namespace, a.getNameSpace(namespace))
+}
+
+// envOverrides 读取prefix开头的环境变量，prefix为空时不读取
+func envOverrides(environ []string, prefix string, mapper func(name string) string) []Override {
+	if prefix == "" {
+		return nil
+	}
+	if mapper == nil {
+		mapper = defaultEnvKeyMapper(prefix)
+	}
+
+	var list []Override
+	for _, kv := range environ {
+		i := strings.Index(kv, "=")
+		if i < 0 || !strings.HasPrefix(kv[:i], prefix) || len(kv[:i]) == len(prefix) {
+			continue
+		}
+
+		name, value := kv[:i], kv[i+1:]
+		list = append(list, Override{Key: mapper(name), Value: value, Source: overrideSourceEnv + name})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Source < list[j].Source })
+	return list
+}
+
+// defaultEnvKeyMapper 去掉前缀后转为小写，"_"替换为"."，例如：APOLLO_OVERRIDE_DB_HOST -> db.host
+func defaultEnvKeyMapper(prefix string) func(name string) string {
+	return func(name string) string {
+		return strings.ReplaceAll(strings.ToLower(strings.TrimPrefix(name, prefix)), "_", ".")
+	}
+}
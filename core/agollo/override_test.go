@@ -0,0 +1,91 @@
+package agollo
+
+import (
+	"flag"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sixgoatsh/agollo/core/apollotest"
+	"github.com/sixgoatsh/agollo/core/config"
+	"github.com/sixgoatsh/agollo/core/options"
+)
+
+func TestOverrides(t *testing.T) {
+	appid := "test"
+	server := apollotest.NewServer(apollotest.Hold(500 * time.Millisecond))
+	defer server.Close()
+	server.Publish(appid, "default", "application", config.Configurations{
+		"db.host": "127.0.0.1",
+		"db.port": "3306",
+		"timeout": "100",
+		"retry":   "3",
+	})
+
+	os.Setenv("AGOLLO_TEST_OVERRIDE_DB_PORT", "3307")
+	os.Setenv("AGOLLO_TEST_OVERRIDE_TIMEOUT", "200")
+	defer os.Unsetenv("AGOLLO_TEST_OVERRIDE_DB_PORT")
+	defer os.Unsetenv("AGOLLO_TEST_OVERRIDE_TIMEOUT")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.String("timeout", "", "")
+	fs.String("unused", "", "")
+
+	a, err := newTestApollo(t, server.URL,
+		options.PreloadNamespaces("application"),
+		options.LongPollerInterval(10*time.Millisecond),
+		options.Override("", map[string]string{"retry": "5", "missing": "1"}),
+		options.Override("application", map[string]string{"extra": "x"}),
+		options.EnvOverrides("AGOLLO_TEST_OVERRIDE_"),
+		options.FlagOverrides(fs),
+	)
+	assert.Nil(t, err)
+	assert.Nil(t, fs.Parse([]string{"-timeout=300"}))
+
+	assert.Equal(t, "127.0.0.1", a.Get("db.host"))
+	assert.Equal(t, "3307", a.Get("db.port"))
+	assert.Equal(t, "300", a.Get("timeout"))
+	assert.Equal(t, "5", a.Get("retry"))
+	assert.Equal(t, "x", a.Get("extra"))
+	// 未指定namespace的覆盖配置不会新增key
+	assert.Empty(t, a.Get("missing"))
+	assert.Equal(t, config.Configurations{
+		"db.host": "127.0.0.1",
+		"db.port": "3307",
+		"timeout": "300",
+		"retry":   "5",
+		"extra":   "x",
+	}, a.GetNameSpace("application"))
+
+	assert.Equal(t, []Override{
+		{Key: "db.port", Value: "3307", Source: "env:AGOLLO_TEST_OVERRIDE_DB_PORT"},
+		{Key: "missing", Value: "1", Source: "options"},
+		{Key: "retry", Value: "5", Source: "options"},
+		{Key: "timeout", Value: "300", Source: "flag:-timeout"},
+		{Namespace: "application", Key: "extra", Value: "x", Source: "options"},
+	}, a.Overrides())
+
+	watchCh := a.Watch()
+	errorsCh := a.Start()
+	defer a.Stop()
+
+	// 被覆盖的key在apollo中的变更不会触发Watch事件
+	time.Sleep(100 * time.Millisecond)
+	server.Publish(appid, "default", "application", config.Configurations{
+		"db.host": "localhost",
+		"db.port": "3308",
+		"timeout": "100",
+		"retry":   "3",
+	})
+
+	select {
+	case resp := <-watchCh:
+		assert.Equal(t, config.Changes{config.NewChange(config.ChangeTypeUpdate, "db.host", "localhost")}, resp.Changes)
+	case err := <-errorsCh:
+		t.Fatal(err.Err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("release should reach Watch() subscribers")
+	}
+}
@@ -0,0 +1,172 @@
+package agollo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sixgoatsh/agollo/pkg/backoff"
+)
+
+// 就绪的namespace的配置来源
+const (
+	loadedFromServer = "server"
+	loadedFromBackup = "backup"
+)
+
+// ErrNotLoaded namespace还未加载过，例如WaitReady开始前没有初始化
+var ErrNotLoaded = errors.New("apollo: namespace not loaded")
+
+// NotReadyError 列出所有未就绪的namespace及最近一次加载失败的原因
+type NotReadyError struct {
+	Namespaces map[string]error // key: namespace
+	Err        error            // WaitReady等待时ctx的错误
+	Init       error            // NewGoApollo返回时为初始化的错误，通常为*InitError，包含未被要求就绪的namespace
+}
+
+func (e *NotReadyError) Error() string {
+	namespaces := make([]string, 0, len(e.Namespaces))
+	for namespace := range e.Namespaces {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+
+	var b strings.Builder
+	b.WriteString("apollo: namespaces not ready")
+	if e.Err != nil {
+		b.WriteString(" (")
+		b.WriteString(e.Err.Error())
+		b.WriteString(")")
+	}
+	for i, namespace := range namespaces {
+		if i == 0 {
+			b.WriteString(": ")
+		} else {
+			b.WriteString("; ")
+		}
+		fmt.Fprintf(&b, "%s: %v", namespace, e.Namespaces[namespace])
+	}
+	return b.String()
+}
+
+// Is 匹配ctx的错误、各namespace的错误及初始化的错误
+func (e *NotReadyError) Is(target error) bool {
+	for _, err := range e.errs() {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As 匹配ctx的错误、各namespace的错误及初始化的错误，例如errors.As(err, &initErr)
+func (e *NotReadyError) As(target interface{}) bool {
+	for _, err := range e.errs() {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *NotReadyError) errs() []error {
+	var errs []error
+	if e.Err != nil {
+		errs = append(errs, e.Err)
+	}
+	if e.Init != nil {
+		errs = append(errs, e.Init)
+	}
+	for _, err := range e.Namespaces {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// Ready RequiredNamespaces都已从apollo或者未过期的备份加载成功时返回nil，可以直接用于readiness探针，
+// 加载成功后即使之后apollo无法连接也保持就绪，继续使用缓存中的配置
+func (a *goApollo) Ready() error {
+	return a.notReadyError(a.opts.RequiredNamespaces, nil)
+}
+
+// WaitReady 阻塞直到namespaces都加载成功，未指定时使用RequiredNamespaces，
+// 等待期间按照RetryPolicy持续重新加载未就绪的namespace，间隔不小于LongPollerInterval，ctx结束时返回*NotReadyError
+func (a *goApollo) WaitReady(ctx context.Context, namespaces ...string) error {
+	if len(namespaces) == 0 {
+		namespaces = a.opts.RequiredNamespaces
+	}
+	// 未初始化的namespace先初始化，错误记录在loadErrors中
+	a.initNamespace(ctx, namespaces...)
+
+	b := backoff.New(a.opts.RetryPolicy)
+	for {
+		pending := a.notReady(namespaces)
+		if len(pending) == 0 {
+			return nil
+		}
+
+		// 至少等待LongPollerInterval，避免RetryPolicy的等待时间过短时频繁请求apollo
+		delay := b.Fail(nil)
+		if delay < a.opts.LongPollerInterval {
+			delay = a.opts.LongPollerInterval
+		}
+		if err := backoff.Sleep(ctx, delay); err != nil {
+			return a.notReadyError(pending, err)
+		}
+		for _, namespace := range pending {
+			a.retryLoad(ctx, namespace)
+		}
+	}
+}
+
+// retryLoad 重新加载还未就绪的namespace，加载成功且配置变化时发送Watch事件
+func (a *goApollo) retryLoad(ctx context.Context, namespace string) {
+	a.reloadLock.Lock()
+	defer a.reloadLock.Unlock()
+
+	oldValue := a.getNameSpace(namespace)
+	_, newValue, err := a.reloadNamespace(ctx, a.balance, a.apolloClient, namespace)
+	if _, ok := a.loaded.Load(namespace); ok && err == nil && len(oldValue.Different(newValue)) > 0 {
+		a.sendWatchCh(namespace, oldValue, newValue)
+	}
+}
+
+func (a *goApollo) notReady(namespaces []string) []string {
+	var pending []string
+	for _, namespace := range namespaces {
+		if _, ok := a.loaded.Load(namespace); !ok {
+			pending = append(pending, namespace)
+		}
+	}
+	return pending
+}
+
+func (a *goApollo) notReadyError(namespaces []string, ctxErr error) error {
+	pending := a.notReady(namespaces)
+	if len(pending) == 0 {
+		return nil
+	}
+
+	e := &NotReadyError{Namespaces: make(map[string]error, len(pending)), Err: ctxErr}
+	for _, namespace := range pending {
+		err, ok := a.loadErrors.Load(namespace)
+		if !ok {
+			err = ErrNotLoaded
+		}
+		e.Namespaces[namespace] = err.(error)
+	}
+	return e
+}
+
+// markLoaded 记录namespace已从source加载成功
+func (a *goApollo) markLoaded(namespace, source string) {
+	a.loaded.Store(namespace, source)
+	a.loadErrors.Delete(namespace)
+}
+
+// markFailed 记录namespace最近一次加载失败的原因
+func (a *goApollo) markFailed(namespace, configServerURL string, status int, err error) {
+	a.loadErrors.Store(namespace, newNamespaceError(namespace, configServerURL, status, err))
+}
@@ -0,0 +1,95 @@
+package agollo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sixgoatsh/agollo/core/apollotest"
+	"github.com/sixgoatsh/agollo/core/backup"
+	"github.com/sixgoatsh/agollo/core/config"
+	"github.com/sixgoatsh/agollo/core/options"
+	"github.com/sixgoatsh/agollo/pkg/backoff"
+)
+
+func TestReady(t *testing.T) {
+	appid := "test"
+	server := apollotest.NewServer(apollotest.Hold(500 * time.Millisecond))
+	defer server.Close()
+	server.Publish(appid, "default", "application", config.Configurations{"timeout": "100"})
+
+	a, err := newTestApollo(t, server.URL,
+		options.RequiredNamespaces("application", "db", "mq"),
+		options.WithRetryPolicy(backoff.Policy{InitialDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond}),
+	)
+	defer a.Stop()
+
+	// 列出所有失败的namespace
+	var notReady *NotReadyError
+	assert.True(t, errors.As(err, &notReady))
+	assert.Len(t, notReady.Namespaces, 2)
+	assert.Contains(t, notReady.Namespaces, "db")
+	assert.Contains(t, notReady.Namespaces, "mq")
+	assert.Contains(t, err.Error(), "db: ")
+	assert.Equal(t, err.Error(), a.Ready().Error())
+	assert.Equal(t, "100", a.Get("timeout"))
+
+	// RetryPolicy的等待时间小于LongPollerInterval时，至少等待LongPollerInterval再重新加载
+	server.ResetRequests()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = a.WaitReady(ctx)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.True(t, errors.As(err, &notReady))
+	assert.Len(t, notReady.Namespaces, 2)
+	assert.Len(t, server.Requests(), 0)
+
+	assert.Nil(t, a.WaitReady(context.Background(), "application"))
+
+	server.Publish(appid, "default", "db", config.Configurations{"dsn": "mysql"})
+	server.Publish(appid, "default", "mq", config.Configurations{"topic": "orders"})
+	changed := make(chan *ApolloResponse, 1)
+	a.AddChangeListener("db", func(resp *ApolloResponse) {
+		changed <- resp
+	})
+
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.Nil(t, a.WaitReady(ctx))
+	assert.Nil(t, a.Ready())
+	assert.Equal(t, "mysql", a.Get("dsn", options.WithNamespace("db")))
+
+	select {
+	case resp := <-changed:
+		assert.Equal(t, config.Configurations{"dsn": "mysql"}, resp.NewValue)
+	case <-time.After(time.Second):
+		t.Fatal("namespaces loaded by WaitReady should reach change listeners")
+	}
+
+	// 未过期的备份同样就绪
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer down.Close()
+
+	store := backup.NewMemoryStore()
+	assert.Nil(t, backup.SaveEntry(store, &backup.Entry{
+		Namespace:      "application",
+		Configurations: config.Configurations{"timeout": "100"},
+		FetchedAt:      time.Now(),
+	}))
+	b, err := newTestApollo(t, down.URL,
+		options.RequiredNamespaces("application"),
+		options.WithBackupStore(store),
+		options.FailTolerantOnBackupExists(),
+		options.WithRetryPolicy(backoff.Policy{MaxAttempts: 1}),
+	)
+	assert.Nil(t, err)
+	assert.Nil(t, b.Ready())
+	b.Stop()
+}
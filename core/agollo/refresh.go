@@ -0,0 +1,130 @@
+package agollo
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sixgoatsh/agollo/core/options"
+)
+
+// RefreshStats 定期刷新的统计，Drifts为刷新时发现配置已变化的次数，即长轮训遗漏的更新
+type RefreshStats struct {
+	Runs    uint64    // 完成的刷新轮数
+	Reloads uint64    // 重新加载namespace的次数
+	Drifts  uint64    // 发现配置变化并发送Watch事件的次数
+	Errors  uint64    // 重新加载失败的次数
+	LastRun time.Time // 最近一轮刷新完成的时间
+}
+
+// refreshLoop 定期重新加载所有已初始化的namespace，长轮训遗漏通知时（例如apollo重启、hold期间网络异常）
+// 仍能获取到最新的配置。RefreshInterval及CacheRefreshInterval共用一个goroutine，按照较短的间隔检查，
+// 到达RefreshInterval时按照各namespace的FetchStrategy重新加载，否则到达CacheRefreshInterval时通过缓存接口刷新
+func (a *goApollo) refreshLoop(ctx context.Context) {
+	interval := a.opts.RefreshInterval
+	if interval <= 0 || (a.opts.CacheRefreshInterval > 0 && a.opts.CacheRefreshInterval < interval) {
+		interval = a.opts.CacheRefreshInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// ticker的触发时间存在误差，相差不到半个间隔时也视为到期
+	due := func(period time.Duration, last, now time.Time) bool {
+		return period > 0 && now.Sub(last) >= period-interval/2
+	}
+
+	lastRefresh := time.Now()
+	lastCacheRefresh := lastRefresh
+	for {
+		select {
+		case now := <-ticker.C:
+			if due(a.opts.RefreshInterval, lastRefresh, now) {
+				// 完整的刷新同样覆盖了缓存接口的刷新
+				lastRefresh, lastCacheRefresh = now, now
+				a.refresh(ctx, a.opts.NamespaceFetchStrategy)
+			} else if due(a.opts.CacheRefreshInterval, lastCacheRefresh, now) {
+				lastCacheRefresh = now
+				a.refresh(ctx, func(string) options.FetchStrategy {
+					return options.FetchCache
+				})
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (a *goApollo) refresh(ctx context.Context, strategy func(namespace string) options.FetchStrategy) {
+	for _, namespace := range a.initializedNamespaces() {
+		if ctx.Err() != nil {
+			return
+		}
+		a.refreshNamespace(ctx, namespace, strategy(namespace), false)
+	}
+
+	a.refreshStatsLock.Lock()
+	a.refreshStats.Runs++
+	a.refreshStats.LastRun = time.Now()
+	a.refreshStatsLock.Unlock()
+}
+
+// refreshNamespace 重新加载namespace，配置变化时发送Watch事件，返回apollo的HTTP Status，
+// force为true时清空缓存的release key，apollo不会返回304，强制重新下载配置，加载失败时恢复原来的release key
+func (a *goApollo) refreshNamespace(ctx context.Context, namespace string, strategy options.FetchStrategy, force bool) (int, error) {
+	a.reloadLock.Lock()
+	defer a.reloadLock.Unlock()
+
+	var oldReleaseKey interface{}
+	if force {
+		oldReleaseKey, _ = a.releaseKeyMap.Load(namespace)
+		a.releaseKeyMap.Store(namespace, "")
+	}
+
+	oldValue := a.getNameSpace(namespace)
+	status, newValue, err := a.loadNamespace(ctx, a.balance, a.apolloClient, namespace, strategy)
+	failed := err != nil || (status != http.StatusOK && status != http.StatusNotModified)
+	if force && failed && oldReleaseKey != nil {
+		a.releaseKeyMap.Store(namespace, oldReleaseKey)
+	}
+	if ctx.Err() != nil {
+		return status, ctx.Err()
+	}
+
+	// 仅在配置有变化时发送，304表示与当前配置一致
+	drifted := err == nil && status == http.StatusOK && len(oldValue.Different(newValue)) > 0
+
+	a.refreshStatsLock.Lock()
+	a.refreshStats.Reloads++
+	if failed {
+		a.refreshStats.Errors++
+	}
+	if drifted {
+		a.refreshStats.Drifts++
+	}
+	a.refreshStatsLock.Unlock()
+
+	metricErr := err
+	if failed && metricErr == nil {
+		metricErr = fmt.Errorf("apollo: refresh namespace %s failed, status: %d", namespace, status)
+	}
+	a.opts.Metrics.Refresh(a.opts.Conf.AppID, a.opts.Conf.ClusterName, namespace, drifted, metricErr)
+
+	if err != nil {
+		a.logger().Warn("refresh namespace failed", "namespace", namespace, "status", status, "error", err)
+		return status, err
+	}
+
+	if drifted {
+		a.logger().Info("configurations changed without notification", "namespace", namespace, "strategy", strategy)
+		a.sendWatchCh(namespace, oldValue, newValue)
+	}
+	return status, nil
+}
+
+// RefreshStats 返回定期刷新的统计，用于观察长轮训遗漏更新的频率
+func (a *goApollo) RefreshStats() RefreshStats {
+	a.refreshStatsLock.Lock()
+	defer a.refreshStatsLock.Unlock()
+	return a.refreshStats
+}
@@ -0,0 +1,135 @@
+package agollo
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sixgoatsh/agollo/core/apollotest"
+	"github.com/sixgoatsh/agollo/core/config"
+	"github.com/sixgoatsh/agollo/core/options"
+)
+
+func TestFetchStrategy(t *testing.T) {
+	appid := "test"
+	server := apollotest.NewServer(apollotest.Hold(500 * time.Millisecond))
+	defer server.Close()
+	server.Publish(appid, "default", "application", config.Configurations{"timeout": "100"})
+	server.Publish(appid, "default", "hot", config.Configurations{"qps": "1000"})
+
+	a, err := newTestApollo(t, server.URL,
+		options.PreloadNamespaces("application", "hot"),
+		options.NamespaceFetchStrategy("hot", options.FetchCache),
+		options.LongPollerInterval(10*time.Millisecond),
+		options.CacheRefreshInterval(50*time.Millisecond),
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, "100", a.Get("timeout"))
+	assert.Equal(t, "1000", a.Get("qps", options.WithNamespace("hot")))
+
+	var paths []string
+	for _, r := range server.Requests() {
+		if r.Path != "/notifications/v2" {
+			paths = append(paths, r.Path)
+		}
+	}
+	assert.Equal(t, []string{"/configs/test/default/application", "/configfiles/json/test/default/hot"}, paths)
+
+	// 长轮训遗漏通知时，定期刷新仍能获取到最新的配置
+	server.Inject(apollotest.Fault{Path: "/notifications/v2", Status: http.StatusNotModified})
+
+	watchCh := a.WatchNamespace("application", make(chan bool))
+	a.Start()
+	defer a.Stop()
+
+	server.Publish(appid, "default", "application", config.Configurations{"timeout": "200"})
+
+	select {
+	case resp := <-watchCh:
+		assert.Equal(t, config.Configurations{"timeout": "100"}, resp.OldValue)
+		assert.Equal(t, config.Configurations{"timeout": "200"}, resp.NewValue)
+	case <-time.After(5 * time.Second):
+		t.Fatal("refresh should reach WatchNamespace() subscribers")
+	}
+	assert.Equal(t, "200", a.Get("timeout"))
+}
+
+func TestRefreshInterval(t *testing.T) {
+	appid := "test"
+	server := apollotest.NewServer(apollotest.Hold(500 * time.Millisecond))
+	defer server.Close()
+	server.Publish(appid, "default", "application", config.Configurations{"timeout": "100"})
+
+	a, err := newTestApollo(t, server.URL,
+		options.PreloadNamespaces("application"),
+		options.LongPollerInterval(10*time.Millisecond),
+		options.RefreshInterval(50*time.Millisecond),
+	)
+	assert.Nil(t, err)
+
+	// 长轮训遗漏通知
+	server.Inject(apollotest.Fault{Path: "/notifications/v2", Status: http.StatusNotModified})
+
+	watchCh := a.Watch()
+	a.Start()
+	defer a.Stop()
+
+	// 配置未变化时使用release key，apollo返回304
+	assert.Eventually(t, func() bool {
+		return a.RefreshStats().Runs >= 2
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, uint64(0), a.RefreshStats().Drifts)
+	for _, r := range server.Requests()[1:] {
+		if r.Path != "/notifications/v2" {
+			assert.Equal(t, "/configs/test/default/application", r.Path)
+			assert.NotEmpty(t, r.Query.Get("releaseKey"))
+		}
+	}
+
+	server.Publish(appid, "default", "application", config.Configurations{"timeout": "200"})
+
+	select {
+	case resp := <-watchCh:
+		assert.Equal(t, config.Configurations{"timeout": "100"}, resp.OldValue)
+		assert.Equal(t, config.Configurations{"timeout": "200"}, resp.NewValue)
+	case <-time.After(5 * time.Second):
+		t.Fatal("refresh should reach Watch() subscribers")
+	}
+
+	stats := a.RefreshStats()
+	assert.Equal(t, uint64(1), stats.Drifts)
+	assert.Equal(t, uint64(0), stats.Errors)
+	assert.False(t, stats.LastRun.IsZero())
+}
+
+func TestRefreshLoop(t *testing.T) {
+	appid := "test"
+	server := apollotest.NewServer(apollotest.Hold(500 * time.Millisecond))
+	defer server.Close()
+	server.Publish(appid, "default", "application", config.Configurations{"timeout": "100"})
+
+	a, err := newTestApollo(t, server.URL,
+		options.PreloadNamespaces("application"),
+		options.RefreshInterval(200*time.Millisecond),
+		options.CacheRefreshInterval(50*time.Millisecond),
+	)
+	assert.Nil(t, err)
+
+	server.ResetRequests()
+	a.Start()
+	defer a.Stop()
+
+	// 同一个goroutine中按照较短的间隔刷新，到达RefreshInterval时使用namespace的FetchStrategy
+	assert.Eventually(t, func() bool {
+		return a.RefreshStats().Runs >= 5
+	}, 5*time.Second, 10*time.Millisecond)
+
+	counts := map[string]int{}
+	for _, r := range server.Requests() {
+		counts[r.Path]++
+	}
+	assert.True(t, counts["/configs/test/default/application"] >= 1)
+	assert.True(t, counts["/configfiles/json/test/default/application"] >= 3)
+}
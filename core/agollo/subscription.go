@@ -0,0 +1,121 @@
+package agollo
+
+import (
+	"context"
+
+	"github.com/sixgoatsh/agollo/core/backup"
+	"github.com/sixgoatsh/agollo/pkg/backoff"
+)
+
+// Subscribe 订阅其他appID及cluster的namespace，返回的GoApollo与当前GoApollo共用ConfigServer负载均衡、
+// 备份存储及错误channel，拥有独立的长轮训，Start及Stop跟随当前GoApollo，变更同时发送到当前GoApollo的Watch，
+// 单独Stop返回的GoApollo会取消订阅
+func (a *goApollo) Subscribe(appID, cluster string, namespaces ...string) (GoApollo, error) {
+	return a.root().subscribe(a.ctx, appID, cluster, namespaces...)
+}
+
+func (a *goApollo) root() *goApollo {
+	if a.parent != nil {
+		return a.parent
+	}
+	return a
+}
+
+func (a *goApollo) subscribe(ctx context.Context, appID, cluster string, namespaces ...string) (*goApollo, error) {
+	if cluster == "" {
+		cluster = a.opts.Conf.ClusterName
+	}
+	if appID == a.opts.Conf.AppID && cluster == a.opts.Conf.ClusterName {
+		return a, a.initNamespace(ctx, namespaces...)
+	}
+
+	key := subscriptionKey(appID, cluster)
+	a.subsLock.Lock()
+	sub, ok := a.subs[key]
+	if !ok {
+		sub = a.newSubscription(appID, cluster)
+		a.subs[key] = sub
+		if a.startCtx != nil {
+			sub.StartCtx(a.startCtx)
+		}
+	}
+	a.subsLock.Unlock()
+
+	return sub, sub.initNamespace(ctx, namespaces...)
+}
+
+func (a *goApollo) newSubscription(appID, cluster string) *goApollo {
+	key := subscriptionKey(appID, cluster)
+	opts := a.opts
+	opts.Conf.AppID = appID
+	opts.Conf.ClusterName = cluster
+	opts.Conf.AccessKey = ""
+	for _, sub := range a.opts.Subscriptions {
+		if sub.AppID == appID && sub.AccessKey != "" {
+			opts.Conf.AccessKey = sub.AccessKey
+		}
+	}
+	opts.PreloadNamespaces = nil
+	opts.NamespaceChain = nil
+	opts.Subscriptions = nil
+	// 共用同一个Store，按照appID及cluster区分备份
+	opts.BackupStore = backup.NewPrefixStore(a.opts.BackupStore, key+"+")
+
+	sub := &goApollo{
+		opts:         opts,
+		apolloClient: a.apolloClient,
+		balance:      a.balance,
+		parent:       a,
+		stopCh:       make(chan struct{}),
+		errorsCh:     a.errorsCh,
+		bindings:     map[string][]*Binding{},
+		envOverrides: a.envOverrides,
+	}
+	sub.ctx, sub.cancel = context.WithCancel(a.ctx)
+	sub.longPollBackoff = backoff.New(opts.RetryPolicy)
+	return sub
+}
+
+// startSubscriptions Start时启动已订阅的appID的长轮训，之后订阅的appID在Subscribe时启动
+func (a *goApollo) startSubscriptions(ctx context.Context) {
+	a.subsLock.Lock()
+	defer a.subsLock.Unlock()
+
+	a.startCtx = ctx
+	for _, sub := range a.subs {
+		sub.StartCtx(ctx)
+	}
+}
+
+func (a *goApollo) stopSubscriptions() {
+	a.subsLock.Lock()
+	subs := make([]*goApollo, 0, len(a.subs))
+	for _, sub := range a.subs {
+		subs = append(subs, sub)
+	}
+	a.subsLock.Unlock()
+
+	// Stop时会从subs中移除，不能持有subsLock
+	for _, sub := range subs {
+		sub.Stop()
+	}
+}
+
+// unsubscribe 从创建它的goApollo中移除已停止的订阅，之后再次Subscribe会重新创建
+func (a *goApollo) unsubscribe() {
+	if a.parent == nil {
+		return
+	}
+
+	key := subscriptionKey(a.opts.Conf.AppID, a.opts.Conf.ClusterName)
+	a.parent.subsLock.Lock()
+	defer a.parent.subsLock.Unlock()
+	if a.parent.subs[key] == a {
+		delete(a.parent.subs, key)
+	}
+}
+
+// subscriptionKey 与apollo的watchedKey一致，格式为：appId+cluster
+func subscriptionKey(appID, cluster string) string {
+	return appID + "+" + cluster
+}
@@ -0,0 +1,87 @@
+package agollo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sixgoatsh/agollo/core/apollotest"
+	"github.com/sixgoatsh/agollo/core/backup"
+	"github.com/sixgoatsh/agollo/core/config"
+	"github.com/sixgoatsh/agollo/core/options"
+)
+
+func TestSubscribe(t *testing.T) {
+	server := apollotest.NewServer(apollotest.Hold(500 * time.Millisecond))
+	defer server.Close()
+	server.Publish("test", "default", "application", config.Configurations{"timeout": "100"})
+	server.Publish("platform", "default", "application", config.Configurations{"timeout": "200"})
+	server.Publish("platform", "gray", "common", config.Configurations{"region": "sh"})
+	server.SetAccessKey("platform", "secret")
+
+	store := backup.NewMemoryStore()
+	a, err := newTestApollo(t, server.URL,
+		options.PreloadNamespaces("application"),
+		options.WithSubscription(options.Subscription{AppID: "platform", Namespaces: []string{"application"}, AccessKey: "secret"}),
+		options.WithBackupStore(store),
+		options.LongPollerInterval(10*time.Millisecond),
+	)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "100", a.Get("timeout"))
+	assert.Equal(t, "200", a.Get("timeout", options.WithAppID("platform")))
+
+	gray, err := a.Subscribe("platform", "gray", "common")
+	assert.Nil(t, err)
+	assert.Equal(t, "sh", gray.Get("region", options.WithNamespace("common")))
+	assert.Equal(t, "sh", a.Get("region", options.WithAppID("platform"), options.WithCluster("gray"), options.WithNamespace("common")))
+	assert.Equal(t, "platform", gray.Options().Conf.AppID)
+
+	// 按照appID及cluster区分备份
+	namespaces, err := store.List()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"application", "platform+default+application", "platform+gray+common"}, namespaces)
+
+	watchCh := a.Watch()
+	listened := make(chan *ApolloResponse, 1)
+	a.AddChangeListener("", func(resp *ApolloResponse) { listened <- resp })
+	errorsCh := a.Start()
+	defer a.Stop()
+
+	time.Sleep(100 * time.Millisecond)
+	server.Publish("platform", "gray", "common", config.Configurations{"region": "bj"})
+
+	select {
+	case resp := <-watchCh:
+		assert.Equal(t, "platform", resp.AppID)
+		assert.Equal(t, "gray", resp.Cluster)
+		assert.Equal(t, "common", resp.Namespace)
+		assert.Equal(t, config.Configurations{"region": "bj"}, resp.NewValue)
+	case err := <-errorsCh:
+		t.Fatal(err.Err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscribed release should reach Watch() subscribers")
+	}
+	select {
+	case resp := <-listened:
+		assert.Equal(t, "platform", resp.AppID)
+		assert.Equal(t, config.Configurations{"region": "bj"}, resp.NewValue)
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscribed release should reach the root change listeners")
+	}
+	assert.Equal(t, "bj", gray.Get("region", options.WithNamespace("common")))
+
+	for _, r := range server.Requests() {
+		if r.Path == "/notifications/v2" {
+			assert.True(t, r.Authorized)
+		}
+	}
+
+	// 单独停止的订阅被移除，再次订阅时重新创建
+	gray.Stop()
+	regray, err := a.Subscribe("platform", "gray", "common")
+	assert.Nil(t, err)
+	assert.True(t, gray != regray)
+	assert.Equal(t, "bj", regray.Get("region", options.WithNamespace("common")))
+}
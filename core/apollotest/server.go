@@ -0,0 +1,403 @@
+// Package apollotest 提供进程内的apollo config service，用于集成测试
+//
+// 实现了/configs、/configfiles/json、/notifications/v2(包括hold语义)及/services/config接口，
+// 可以发布、删除namespace，注入延迟及失败，并校验请求的签名
+package apollotest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sixgoatsh/agollo/core/auth"
+	"github.com/sixgoatsh/agollo/core/client"
+	"github.com/sixgoatsh/agollo/core/config"
+)
+
+var (
+	defaultHold = 60 * time.Second
+)
+
+const propertiesSuffix = ".properties"
+
+// Fault 注入到路径前缀匹配Path的请求
+type Fault struct {
+	Path    string        // 请求路径前缀，例如/configs、/notifications/v2，为空时匹配所有请求
+	Latency time.Duration // 处理请求前等待的时间
+	Status  int           // 非0时直接返回该HTTP Status
+	Times   int           // 生效的次数，小于等于0时一直生效，直到ClearFaults
+}
+
+// Request 服务收到的请求
+type Request struct {
+	Method     string
+	Path       string
+	Query      url.Values
+	Header     http.Header
+	Authorized bool // 签名校验是否通过，未设置AccessKey的appID总是通过
+}
+
+type release struct {
+	releaseKey     string
+	configurations config.Configurations
+}
+
+type Server struct {
+	URL string
+
+	server *httptest.Server
+	hold   time.Duration
+
+	mu              sync.Mutex
+	releases        map[string]*release // key: appID+cluster+namespace
+	notificationIDs map[string]int      // key: appID+cluster+namespace，删除namespace后保留
+	accessKeys      map[string]string   // key: appID
+	configServers   []client.ConfigServerResp
+	faults          []*Fault
+	requests        []Request
+	changed         chan struct{}
+}
+
+type Option func(*Server)
+
+// Hold 没有变更时/notifications/v2请求被hold的时间，默认：60s
+func Hold(d time.Duration) Option {
+	return func(s *Server) {
+		s.hold = d
+	}
+}
+
+// NewServer 启动服务，使用完后需要调用Close
+func NewServer(opts ...Option) *Server {
+	s := &Server{
+		hold:            defaultHold,
+		releases:        map[string]*release{},
+		notificationIDs: map[string]int{},
+		accessKeys:      map[string]string{},
+		changed:         make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.server = httptest.NewServer(s)
+	s.URL = s.server.URL
+	return s
+}
+
+// Close 关闭服务，会等待所有请求结束，被hold住的请求需要客户端中断
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// Publish 发布namespace的配置，返回新的release key，被hold的/notifications/v2请求会立即返回
+func (s *Server) Publish(appID, cluster, namespace string, conf config.Configurations) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := watchedKey(appID, cluster, namespace)
+	s.notificationIDs[key]++
+	releaseKey := fmt.Sprintf("%s-%d", time.Now().Format("20060102150405"), s.notificationIDs[key])
+
+	c := make(config.Configurations, len(conf))
+	for k, v := range conf {
+		c[k] = v
+	}
+	s.releases[key] = &release{releaseKey: releaseKey, configurations: c}
+
+	s.notifyLocked()
+	return releaseKey
+}
+
+// Delete 删除namespace，之后请求该namespace的配置返回404
+func (s *Server) Delete(appID, cluster, namespace string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := watchedKey(appID, cluster, namespace)
+	if _, ok := s.releases[key]; !ok {
+		return
+	}
+	delete(s.releases, key)
+	s.notificationIDs[key]++
+
+	s.notifyLocked()
+}
+
+// SetAccessKey 开启appID的访问密钥校验，签名错误的请求返回401
+func (s *Server) SetAccessKey(appID, accessKey string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.accessKeys[appID] = accessKey
+}
+
+// SetConfigServers 设置/services/config返回的ConfigServer列表，默认返回服务自身
+func (s *Server) SetConfigServers(urls ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.configServers = nil
+	for i, u := range urls {
+		s.configServers = append(s.configServers, client.ConfigServerResp{
+			AppName:     "APOLLO-CONFIGSERVICE",
+			InstanceID:  fmt.Sprintf("apollotest:apollo-configservice:%d", i),
+			HomePageURL: u,
+		})
+	}
+}
+
+// Inject 注入延迟或失败，多个Fault同时匹配时按照注入顺序依次生效
+func (s *Server) Inject(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &f)
+}
+
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// Requests 返回服务收到的所有请求
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+func (s *Server) ResetRequests() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	appID := requestAppID(r)
+
+	s.mu.Lock()
+	authorized := auth.Verify(s.accessKeys[appID], appID, r.RequestURI,
+		r.Header.Get(auth.HTTP_HEADER_AUTHORIZATION), r.Header.Get(auth.HTTP_HEADER_TIMESTAMP))
+	s.requests = append(s.requests, Request{
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		Query:      r.URL.Query(),
+		Header:     r.Header.Clone(),
+		Authorized: authorized,
+	})
+	faults := s.matchFaultsLocked(r.URL.Path)
+	s.mu.Unlock()
+
+	for _, f := range faults {
+		if f.Latency > 0 {
+			select {
+			case <-time.After(f.Latency):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		if f.Status != 0 {
+			w.WriteHeader(f.Status)
+			return
+		}
+	}
+
+	if !authorized {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/configs/"):
+		s.serveConfigs(w, r)
+	case strings.HasPrefix(r.URL.Path, "/configfiles/json/"):
+		s.serveConfigFiles(w, r)
+	case r.URL.Path == "/notifications/v2":
+		s.serveNotifications(w, r)
+	case r.URL.Path == "/services/config":
+		s.serveConfigServers(w)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// serveConfigs /configs/{appId}/{cluster}/{namespace}?releaseKey=
+func (s *Server) serveConfigs(w http.ResponseWriter, r *http.Request) {
+	appID, cluster, namespace, ok := splitPath(strings.TrimPrefix(r.URL.Path, "/configs/"))
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	rel, found := s.releases[watchedKey(appID, cluster, namespace)]
+	s.mu.Unlock()
+	if !found {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if r.URL.Query().Get("releaseKey") == rel.releaseKey {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	writeJSON(w, &client.NonCacheResp{
+		AppID:          appID,
+		Cluster:        cluster,
+		NamespaceName:  trimNamespace(namespace),
+		Configurations: rel.configurations,
+		ReleaseKey:     rel.releaseKey,
+	})
+}
+
+// serveConfigFiles /configfiles/json/{appId}/{cluster}/{namespace}
+func (s *Server) serveConfigFiles(w http.ResponseWriter, r *http.Request) {
+	appID, cluster, namespace, ok := splitPath(strings.TrimPrefix(r.URL.Path, "/configfiles/json/"))
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	rel, found := s.releases[watchedKey(appID, cluster, namespace)]
+	s.mu.Unlock()
+	if !found {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, rel.configurations)
+}
+
+// serveNotifications 请求的notificationID与服务端不一致时立即返回，否则hold直到有变更或超时后返回304
+func (s *Server) serveNotifications(w http.ResponseWriter, r *http.Request) {
+	appID := r.URL.Query().Get("appId")
+	cluster := r.URL.Query().Get("cluster")
+
+	var req []config.Notification
+	if err := json.Unmarshal([]byte(r.URL.Query().Get("notifications")), &req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	timeout := time.NewTimer(s.hold)
+	defer timeout.Stop()
+
+	for {
+		var resp []config.Notification
+		s.mu.Lock()
+		for _, n := range req {
+			key := watchedKey(appID, cluster, n.NamespaceName)
+			id, ok := s.notificationIDs[key]
+			if !ok || id == n.NotificationID {
+				continue
+			}
+			resp = append(resp, config.Notification{
+				NamespaceName:  trimNamespace(n.NamespaceName),
+				NotificationID: id,
+				Messages: &config.NotificationMessages{
+					Details: map[string]int64{key: int64(id)},
+				},
+			})
+		}
+		changed := s.changed
+		s.mu.Unlock()
+
+		if len(resp) > 0 {
+			writeJSON(w, resp)
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-timeout.C:
+			w.WriteHeader(http.StatusNotModified)
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func (s *Server) serveConfigServers(w http.ResponseWriter) {
+	s.mu.Lock()
+	css := s.configServers
+	s.mu.Unlock()
+
+	if css == nil {
+		css = []client.ConfigServerResp{{
+			AppName:     "APOLLO-CONFIGSERVICE",
+			InstanceID:  "apollotest:apollo-configservice",
+			HomePageURL: s.URL,
+		}}
+	}
+	writeJSON(w, css)
+}
+
+func (s *Server) matchFaultsLocked(path string) []Fault {
+	var (
+		matched []Fault
+		faults  []*Fault
+	)
+	for _, f := range s.faults {
+		if !strings.HasPrefix(path, f.Path) {
+			faults = append(faults, f)
+			continue
+		}
+
+		matched = append(matched, *f)
+		if f.Times > 0 {
+			f.Times--
+			if f.Times == 0 {
+				continue
+			}
+		}
+		faults = append(faults, f)
+	}
+	s.faults = faults
+	return matched
+}
+
+func (s *Server) notifyLocked() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// requestAppID 配置接口的appId在路径中，其他接口在参数中
+func requestAppID(r *http.Request) string {
+	for _, prefix := range []string{"/configs/", "/configfiles/json/"} {
+		if strings.HasPrefix(r.URL.Path, prefix) {
+			appID, _, _, _ := splitPath(strings.TrimPrefix(r.URL.Path, prefix))
+			return appID
+		}
+	}
+	return r.URL.Query().Get("appId")
+}
+
+func splitPath(p string) (appID, cluster, namespace string, ok bool) {
+	parts := strings.Split(p, "/")
+	if len(parts) != 3 {
+		return "", "", "", false
+	}
+	return parts[0], parts[1], parts[2], true
+}
+
+// watchedKey 与apollo一致，格式为：appId+cluster+namespace，properties格式的namespace不带后缀
+func watchedKey(appID, cluster, namespace string) string {
+	return appID + "+" + cluster + "+" + trimNamespace(namespace)
+}
+
+func trimNamespace(namespace string) string {
+	return strings.TrimSuffix(namespace, propertiesSuffix)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,146 @@
+package apollotest
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sixgoatsh/agollo/core/client"
+	"github.com/sixgoatsh/agollo/core/config"
+)
+
+func TestServer(t *testing.T) {
+	s := NewServer(Hold(200 * time.Millisecond))
+	defer s.Close()
+
+	c := client.New()
+	conf := config.DefaultConfig(s.URL, "test")
+	conf.NamespaceName = "application"
+
+	// 未发布的namespace
+	status, _, err := c.GetConfigsFromNonCache(conf)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusNotFound, status)
+
+	releaseKey := s.Publish("test", "default", "application", config.Configurations{"timeout": "100"})
+
+	status, resp, err := c.GetConfigsFromNonCache(conf)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, releaseKey, resp.ReleaseKey)
+	assert.Equal(t, config.Configurations{"timeout": "100"}, resp.Configurations)
+
+	status, _, err = c.GetConfigsFromNonCache(conf, client.ReleaseKey(releaseKey))
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusNotModified, status)
+
+	cached, err := c.GetConfigsFromCache(conf)
+	assert.Nil(t, err)
+	assert.Equal(t, config.Configurations{"timeout": "100"}, *cached)
+
+	// notificationID不一致时立即返回
+	conf.Notifications = config.Notifications{{NamespaceName: "application", NotificationID: -1}}
+	status, notifications, err := c.GetNotifications(conf)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, notifications, 1)
+	assert.Equal(t, 1, notifications[0].NotificationID)
+	assert.Equal(t, map[string]int64{"test+default+application": 1}, notifications[0].Messages.Details)
+
+	// 没有变更时hold到超时
+	conf.Notifications = config.Notifications{{NamespaceName: "application", NotificationID: 1}}
+	start := time.Now()
+	status, _, err = c.GetNotifications(conf)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusNotModified, status)
+	assert.True(t, time.Since(start) >= 200*time.Millisecond)
+
+	// 删除后被hold的请求立即返回
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		s.Delete("test", "default", "application")
+	}()
+	status, notifications, err = c.GetNotifications(conf)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, 2, notifications[0].NotificationID)
+
+	status, _, err = c.GetConfigsFromNonCache(conf)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusNotFound, status)
+
+	// ConfigServer列表
+	status, css, err := c.GetConfigServers(conf)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, s.URL, css[0].HomePageURL)
+
+	s.SetConfigServers("http://127.0.0.1:8080", "http://127.0.0.1:8081")
+	_, css, _ = c.GetConfigServers(conf)
+	assert.Len(t, css, 2)
+}
+
+func TestServerAccessKey(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	s.Publish("test", "default", "application", config.Configurations{"timeout": "100"})
+	s.SetAccessKey("test", "secret")
+
+	c := client.New()
+	conf := config.DefaultConfig(s.URL, "test")
+	conf.NamespaceName = "application"
+
+	status, _, err := c.GetConfigsFromNonCache(conf)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	conf.AccessKey = "wrong"
+	status, _, _ = c.GetConfigsFromNonCache(conf)
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	conf.AccessKey = "secret"
+	status, _, err = c.GetConfigsFromNonCache(conf)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+
+	requests := s.Requests()
+	assert.Len(t, requests, 3)
+	assert.False(t, requests[0].Authorized)
+	assert.False(t, requests[1].Authorized)
+	assert.True(t, requests[2].Authorized)
+	assert.Equal(t, "/configs/test/default/application", requests[2].Path)
+}
+
+func TestServerFaults(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	s.Publish("test", "default", "application", config.Configurations{"timeout": "100"})
+
+	c := client.New()
+	conf := config.DefaultConfig(s.URL, "test")
+	conf.NamespaceName = "application"
+
+	s.Inject(Fault{Path: "/configs", Status: http.StatusServiceUnavailable, Times: 2})
+	for i := 0; i < 2; i++ {
+		status, _, _ := c.GetConfigsFromNonCache(conf)
+		assert.Equal(t, http.StatusServiceUnavailable, status)
+	}
+	status, _, _ := c.GetConfigsFromNonCache(conf)
+	assert.Equal(t, http.StatusOK, status)
+
+	// 延迟超过ctx的超时时间
+	s.Inject(Fault{Latency: time.Second})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, _, err := c.GetConfigsFromNonCacheCtx(ctx, conf)
+	assert.NotNil(t, err)
+
+	s.ClearFaults()
+	status, _, _ = c.GetConfigsFromNonCache(conf)
+	assert.Equal(t, http.StatusOK, status)
+}
@@ -38,3 +38,16 @@ func HttpHeader(accessKey string, appID, uri string) map[string]string {
 
 	return headers
 }
+
+// Verify 校验HttpHeader生成的签名，accessKey为空时不校验
+func Verify(accessKey, appID, uri, authorization, timestamp string) bool {
+	if accessKey == "" {
+		return true
+	}
+	if timestamp == "" {
+		return false
+	}
+
+	expected := fmt.Sprintf(AUTHORIZATION_FORMAT, appID, signature(timestamp, uri, accessKey))
+	return hmac.Equal([]byte(expected), []byte(authorization))
+}
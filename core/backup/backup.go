@@ -0,0 +1,96 @@
+package backup
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sixgoatsh/agollo/core/config"
+)
+
+var (
+	// ErrNotFound namespace没有备份
+	ErrNotFound = errors.New("backup not found")
+	// ErrExpired 备份的时间超过了MaxBackupAge，或者旧版本的备份没有记录时间
+	ErrExpired = errors.New("backup expired")
+	// ErrCorrupt 备份文件无法解析
+	ErrCorrupt = errors.New("backup corrupt")
+)
+
+// Entry 一个namespace的备份
+type Entry struct {
+	Namespace      string                `json:"namespace"`
+	ReleaseKey     string                `json:"releaseKey"`
+	Configurations config.Configurations `json:"configurations"`
+
+	// 以下元数据仅在Store实现了EntrySaver时保存
+	NotificationID int       `json:"notificationId,omitempty"` // 备份时的notificationID，可能比实际的旧，但不会更新
+	AppID          string    `json:"appId,omitempty"`
+	Cluster        string    `json:"cluster,omitempty"`
+	FetchedAt      time.Time `json:"fetchedAt,omitempty"` // 从apollo获取配置的时间
+}
+
+// Expired 备份是否超过maxAge，maxAge小于等于0时不过期，没有记录时间的备份视为过期
+func (e *Entry) Expired(maxAge time.Duration, now time.Time) bool {
+	if maxAge <= 0 {
+		return false
+	}
+	return e.FetchedAt.IsZero() || now.Sub(e.FetchedAt) > maxAge
+}
+
+// Store 备份apollo的配置，在apollo无法连接且开启FailTolerantOnBackupExists时从备份中读取
+// 实现需要保证并发安全
+type Store interface {
+	// Save 保存namespace的最新配置，覆盖旧的备份
+	Save(namespace, releaseKey string, conf config.Configurations) error
+	// Load 读取namespace的备份，没有备份时返回ErrNotFound
+	Load(namespace string) (*Entry, error)
+	// List 返回所有已备份的namespace
+	List() ([]string, error)
+}
+
+// EntrySaver Store的可选接口，实现后可以保存包括元数据在内的完整备份
+type EntrySaver interface {
+	SaveEntry(entry *Entry) error
+}
+
+// SaveEntry store实现了EntrySaver时保存完整的备份，否则仅保存release key及配置
+func SaveEntry(store Store, entry *Entry) error {
+	if saver, ok := store.(EntrySaver); ok {
+		return saver.SaveEntry(entry)
+	}
+	return store.Save(entry.Namespace, entry.ReleaseKey, entry.Configurations)
+}
+
+// writeFileAtomic 先写入同目录下的临时文件再rename，避免进程中断时留下不完整的备份
+func writeFileAtomic(filename string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(filename)
+	if err := os.MkdirAll(dir, 0777); err != nil && !os.IsExist(err) {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(dir, "."+filepath.Base(filename)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), filename)
+}
@@ -0,0 +1,228 @@
+package backup
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sixgoatsh/agollo/core/config"
+)
+
+func TestStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var tests = []struct {
+		Name            string
+		Store           Store
+		KeepsReleaseKey bool
+	}{
+		{"file", NewFileStore(filepath.Join(dir, "file", ".goApollo")), false},
+		{"dir", NewDirStore(filepath.Join(dir, "dir")), true},
+		{"memory", NewMemoryStore(), true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			namespaces, err := test.Store.List()
+			assert.Nil(t, err)
+			assert.Empty(t, namespaces)
+
+			_, err = test.Store.Load("application")
+			assert.Equal(t, ErrNotFound, err)
+
+			assert.Nil(t, test.Store.Save("application", "r1", config.Configurations{"a": "1"}))
+			assert.Nil(t, test.Store.Save("dev/app.json", "r2", config.Configurations{"b": "2"}))
+			assert.Nil(t, test.Store.Save("application", "r3", config.Configurations{"a": "3"}))
+
+			entry, err := test.Store.Load("application")
+			assert.Nil(t, err)
+			assert.Equal(t, "application", entry.Namespace)
+			assert.Equal(t, config.Configurations{"a": "3"}, entry.Configurations)
+			if test.KeepsReleaseKey {
+				assert.Equal(t, "r3", entry.ReleaseKey)
+			}
+
+			entry, err = test.Store.Load("dev/app.json")
+			assert.Nil(t, err)
+			assert.Equal(t, config.Configurations{"b": "2"}, entry.Configurations)
+
+			namespaces, err = test.Store.List()
+			assert.Nil(t, err)
+			assert.Equal(t, []string{"application", "dev/app.json"}, namespaces)
+		})
+	}
+
+	// 原子写入不会残留临时文件
+	files, err := ioutil.ReadDir(filepath.Join(dir, "dir"))
+	assert.Nil(t, err)
+	assert.Len(t, files, 2)
+}
+
+func TestFileStoreCompatible(t *testing.T) {
+	f, err := ioutil.TempFile("", "backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.WriteString(`{"application":{"timeout":"100"},"redis":{"host":"127.0.0.1"}}`)
+	assert.Nil(t, err)
+	assert.Nil(t, f.Close())
+
+	store := NewFileStore(f.Name())
+	entry, err := store.Load("redis")
+	assert.Nil(t, err)
+	assert.Equal(t, config.Configurations{"host": "127.0.0.1"}, entry.Configurations)
+
+	// 更新时保留其他namespace
+	assert.Nil(t, store.Save("redis", "", config.Configurations{"host": "localhost"}))
+	entry, err = NewFileStore(f.Name()).Load("application")
+	assert.Nil(t, err)
+	assert.Equal(t, config.Configurations{"timeout": "100"}, entry.Configurations)
+
+	// 损坏的文件移动到.bak后重新备份
+	assert.Nil(t, ioutil.WriteFile(f.Name(), []byte(`{"application":`), 0666))
+	defer os.Remove(f.Name() + ".bak")
+	store = NewFileStore(f.Name())
+	_, err = store.Load("application")
+	assert.True(t, errors.Is(err, ErrCorrupt))
+	assert.Nil(t, store.Save("redis", "", config.Configurations{"host": "localhost"}))
+	corrupt, err := ioutil.ReadFile(f.Name() + ".bak")
+	assert.Nil(t, err)
+	assert.Equal(t, `{"application":`, string(corrupt))
+	entry, err = NewFileStore(f.Name()).Load("redis")
+	assert.Nil(t, err)
+	assert.Equal(t, config.Configurations{"host": "localhost"}, entry.Configurations)
+
+	// 无法读取的文件不会被覆盖
+	_, err = NewFileStore(os.TempDir()).Load("application")
+	assert.NotNil(t, err)
+	assert.NotNil(t, NewFileStore(os.TempDir()).Save("redis", "", config.Configurations{"host": "localhost"}))
+}
+
+func TestDirStoreCorrupt(t *testing.T) {
+	dir, err := ioutil.TempDir("", "backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// 与fileStore一致，损坏的文件返回ErrCorrupt，移动到.bak后重新备份
+	filename := filepath.Join(dir, "application"+dirStoreExt)
+	assert.Nil(t, ioutil.WriteFile(filename, []byte(`{"releaseKey":`), 0666))
+	store := NewDirStore(dir)
+	_, err = store.Load("application")
+	assert.True(t, errors.Is(err, ErrCorrupt))
+
+	assert.Nil(t, store.Save("application", "r1", config.Configurations{"timeout": "100"}))
+	corrupt, err := ioutil.ReadFile(filename + ".bak")
+	assert.Nil(t, err)
+	assert.Equal(t, `{"releaseKey":`, string(corrupt))
+	entry, err := store.Load("application")
+	assert.Nil(t, err)
+	assert.Equal(t, "r1", entry.ReleaseKey)
+
+	namespaces, err := store.List()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"application"}, namespaces)
+
+	// 空文件等同于没有备份
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "redis"+dirStoreExt), nil, 0666))
+	_, err = store.Load("redis")
+	assert.Equal(t, ErrNotFound, err)
+}
+
+func TestNopStore(t *testing.T) {
+	store := NewNopStore()
+	assert.Nil(t, store.Save("application", "", config.Configurations{"a": "1"}))
+
+	_, err := store.Load("application")
+	assert.Equal(t, ErrNotFound, err)
+}
+
+func TestSaveEntry(t *testing.T) {
+	dir, err := ioutil.TempDir("", "backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fetchedAt := time.Now().Add(-time.Hour).Truncate(time.Second)
+	expected := &Entry{
+		Namespace:      "application",
+		ReleaseKey:     "r1",
+		Configurations: config.Configurations{"a": "1"},
+		NotificationID: 10,
+		AppID:          "test",
+		Cluster:        "default",
+		FetchedAt:      fetchedAt,
+	}
+
+	for _, newStore := range []func() Store{
+		func() Store { return NewFileStore(filepath.Join(dir, ".goApollo")) },
+		func() Store { return NewDirStore(filepath.Join(dir, "dir")) },
+		func() Store {
+			return NewEncryptedStore(NewDirStore(filepath.Join(dir, "encrypted")), StaticKey(newKey))
+		},
+	} {
+		assert.Nil(t, SaveEntry(newStore(), expected))
+
+		entry, err := newStore().Load("application")
+		assert.Nil(t, err)
+		assert.True(t, fetchedAt.Equal(entry.FetchedAt))
+		entry.FetchedAt = fetchedAt
+		assert.Equal(t, expected, entry)
+
+		assert.False(t, entry.Expired(0, time.Now()))
+		assert.False(t, entry.Expired(2*time.Hour, time.Now()))
+		assert.True(t, entry.Expired(time.Minute, time.Now()))
+	}
+
+	// 元数据不影响旧格式的读取
+	namespaces, err := NewFileStore(filepath.Join(dir, ".goApollo")).List()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"application"}, namespaces)
+
+	// 旧格式没有记录时间，视为过期
+	assert.True(t, (&Entry{}).Expired(time.Hour, time.Now()))
+}
+
+func TestPrefixStore(t *testing.T) {
+	store := NewMemoryStore()
+	assert.Nil(t, store.Save("application", "r1", config.Configurations{"a": "1"}))
+
+	platform := NewPrefixStore(store, "platform+default+")
+	assert.Nil(t, SaveEntry(platform, &Entry{
+		Namespace:      "application",
+		ReleaseKey:     "r2",
+		Configurations: config.Configurations{"a": "2"},
+		AppID:          "platform",
+	}))
+
+	entry, err := platform.Load("application")
+	assert.Nil(t, err)
+	assert.Equal(t, "application", entry.Namespace)
+	assert.Equal(t, "platform", entry.AppID)
+	assert.Equal(t, config.Configurations{"a": "2"}, entry.Configurations)
+
+	// 不影响未加前缀的备份
+	entry, err = store.Load("application")
+	assert.Nil(t, err)
+	assert.Equal(t, config.Configurations{"a": "1"}, entry.Configurations)
+
+	namespaces, err := platform.List()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"application"}, namespaces)
+
+	_, err = platform.Load("other")
+	assert.Equal(t, ErrNotFound, err)
+}
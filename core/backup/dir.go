@@ -0,0 +1,113 @@
+package backup
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sixgoatsh/agollo/core/config"
+)
+
+const dirStoreExt = ".json"
+
+// dirStore 每个namespace保存为目录下的一个文件，更新某个namespace时不需要重写其他namespace
+type dirStore struct {
+	dir string
+	mu  sync.RWMutex
+}
+
+func NewDirStore(dir string) Store {
+	return &dirStore{dir: dir}
+}
+
+func (s *dirStore) Save(namespace, releaseKey string, conf config.Configurations) error {
+	return s.SaveEntry(&Entry{
+		Namespace:      namespace,
+		ReleaseKey:     releaseKey,
+		Configurations: conf,
+	})
+}
+
+func (s *dirStore) SaveEntry(entry *Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	filename := s.filename(entry.Namespace)
+	if _, err = s.load(entry.Namespace); errors.Is(err, ErrCorrupt) {
+		// 与fileStore一致，文件损坏时移动到.bak后重新备份，保留损坏的文件用于排查
+		if err = os.Rename(filename, filename+".bak"); err != nil {
+			return err
+		}
+	}
+	return writeFileAtomic(filename, data, 0666)
+}
+
+func (s *dirStore) Load(namespace string) (*Entry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.load(namespace)
+}
+
+func (s *dirStore) load(namespace string) (*Entry, error) {
+	filename := s.filename(namespace)
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) || (err == nil && len(data) == 0) {
+		// 空文件等同于没有备份
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entry Entry
+	if err = json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrCorrupt, filename, err)
+	}
+	entry.Namespace = namespace
+	return &entry, nil
+}
+
+func (s *dirStore) List() ([]string, error) {
+	s.mu.RLock()
+	files, err := ioutil.ReadDir(s.dir)
+	s.mu.RUnlock()
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var namespaces []string
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || strings.HasPrefix(name, ".") || !strings.HasSuffix(name, dirStoreExt) {
+			continue
+		}
+
+		namespace, err := url.PathUnescape(strings.TrimSuffix(name, dirStoreExt))
+		if err != nil {
+			continue
+		}
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+	return namespaces, nil
+}
+
+// filename namespace可能包含路径分隔符，需要转义
+func (s *dirStore) filename(namespace string) string {
+	return filepath.Join(s.dir, url.PathEscape(namespace)+dirStoreExt)
+}
@@ -0,0 +1,282 @@
+package backup
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/sixgoatsh/agollo/core/config"
+)
+
+const (
+	// encryptedKey 加密后的配置以该key保存在内部Store中
+	encryptedKey = "__agollo_encrypted__"
+	// v1仅加密配置，release key等元数据未经认证，不再读取
+	encryptedVersion = "v2:"
+)
+
+var (
+	// ErrDecrypt 所有key都无法解密备份，通常是key配置错误或备份被篡改
+	ErrDecrypt = errors.New("backup: unable to decrypt backup, wrong key or corrupted data")
+	// ErrInvalidKey key长度必须为16、24或32字节(AES-128/192/256)
+	ErrInvalidKey = errors.New("backup: invalid key, must be 16, 24 or 32 bytes, raw or base64/hex encoded")
+	// ErrPlaintext 加密的Store中读取到未加密的备份，可能是被替换的备份，开启AllowPlaintextMigration后才读取
+	ErrPlaintext = errors.New("backup: unencrypted backup in encrypted store")
+)
+
+// KeyProvider 提供加密备份的key，current用于加密，解密时依次尝试current及previous，
+// 使用previous解密成功的备份会用current重新加密
+type KeyProvider interface {
+	Keys() (current []byte, previous [][]byte, err error)
+}
+
+// KeyProviderFunc 将普通函数转换为KeyProvider
+type KeyProviderFunc func() ([]byte, [][]byte, error)
+
+func (f KeyProviderFunc) Keys() ([]byte, [][]byte, error) {
+	return f()
+}
+
+// StaticKey 使用固定的key
+func StaticKey(current []byte, previous ...[]byte) KeyProvider {
+	return KeyProviderFunc(func() ([]byte, [][]byte, error) {
+		return current, previous, nil
+	})
+}
+
+// EnvKey 从环境变量读取key，previousNames为轮换前的key所在的环境变量
+func EnvKey(name string, previousNames ...string) KeyProvider {
+	return KeyProviderFunc(func() ([]byte, [][]byte, error) {
+		return readKeys(func(name string) (string, error) {
+			val, ok := os.LookupEnv(name)
+			if !ok {
+				return "", fmt.Errorf("backup: environment variable %s is not set", name)
+			}
+			return val, nil
+		}, name, previousNames)
+	})
+}
+
+// FileKey 从文件读取key，previousFiles为轮换前的key所在的文件
+func FileKey(filename string, previousFiles ...string) KeyProvider {
+	return KeyProviderFunc(func() ([]byte, [][]byte, error) {
+		return readKeys(func(filename string) (string, error) {
+			data, err := ioutil.ReadFile(filename)
+			return string(data), err
+		}, filename, previousFiles)
+	})
+}
+
+func readKeys(read func(string) (string, error), current string, previous []string) ([]byte, [][]byte, error) {
+	val, err := read(current)
+	if err != nil {
+		return nil, nil, err
+	}
+	currentKey, err := parseKey(val)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var previousKeys [][]byte
+	for _, name := range previous {
+		val, err := read(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		key, err := parseKey(val)
+		if err != nil {
+			return nil, nil, err
+		}
+		previousKeys = append(previousKeys, key)
+	}
+
+	return currentKey, previousKeys, nil
+}
+
+// parseKey 依次尝试hex、base64编码，都不是时作为原始字节
+func parseKey(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if key, err := hex.DecodeString(s); err == nil && validKeyLength(len(key)) {
+		return key, nil
+	}
+	if key, err := base64.StdEncoding.DecodeString(s); err == nil && validKeyLength(len(key)) {
+		return key, nil
+	}
+	if validKeyLength(len(s)) {
+		return []byte(s), nil
+	}
+	return nil, ErrInvalidKey
+}
+
+func validKeyLength(n int) bool {
+	return n == 16 || n == 24 || n == 32
+}
+
+// encryptedStore 使用AES-GCM加密整个备份后保存到内部Store，release key、notificationID及获取时间同样参与认证，
+// namespace作为附加数据，防止不同namespace的备份被互相替换
+type encryptedStore struct {
+	store          Store
+	keys           KeyProvider
+	allowPlaintext bool
+}
+
+type EncryptOption func(*encryptedStore)
+
+// AllowPlaintextMigration 允许读取开启加密前的明文备份，读取后加密重新保存，
+// 仅在迁移已有的明文备份时开启，否则能写入备份的人可以用明文备份替换配置
+func AllowPlaintextMigration() EncryptOption {
+	return func(s *encryptedStore) {
+		s.allowPlaintext = true
+	}
+}
+
+// NewEncryptedStore 加密保存到store中的配置，默认读取到未加密的备份时返回ErrPlaintext
+func NewEncryptedStore(store Store, keys KeyProvider, opts ...EncryptOption) Store {
+	s := &encryptedStore{store: store, keys: keys}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *encryptedStore) Save(namespace, releaseKey string, conf config.Configurations) error {
+	return s.SaveEntry(&Entry{
+		Namespace:      namespace,
+		ReleaseKey:     releaseKey,
+		Configurations: conf,
+	})
+}
+
+// SaveEntry 加密整个备份，内部Store中仅保存namespace及密文
+func (s *encryptedStore) SaveEntry(entry *Entry) error {
+	current, _, err := s.keys.Keys()
+	if err != nil {
+		return err
+	}
+
+	ciphertext, err := encrypt(current, entry)
+	if err != nil {
+		return err
+	}
+
+	return SaveEntry(s.store, &Entry{
+		Namespace:      entry.Namespace,
+		Configurations: config.Configurations{encryptedKey: ciphertext},
+	})
+}
+
+func (s *encryptedStore) Load(namespace string) (*Entry, error) {
+	entry, err := s.store.Load(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	current, previous, err := s.keys.Keys()
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext, ok := entry.Configurations[encryptedKey].(string)
+	if !ok {
+		if !s.allowPlaintext {
+			return nil, fmt.Errorf("%w: namespace %s", ErrPlaintext, namespace)
+		}
+		// 开启加密前的明文备份，加密后重新保存，保存失败时下次读取再重试
+		_ = s.SaveEntry(entry)
+		return entry, nil
+	}
+
+	for i, key := range append([][]byte{current}, previous...) {
+		decrypted, err := decrypt(key, namespace, ciphertext)
+		if err == ErrInvalidKey {
+			return nil, err
+		}
+		if err != nil {
+			continue
+		}
+
+		entry = decrypted
+		if i > 0 {
+			// 使用旧key解密成功，用新key重新加密
+			_ = s.SaveEntry(entry)
+		}
+		return entry, nil
+	}
+
+	return nil, fmt.Errorf("%w: namespace %s", ErrDecrypt, namespace)
+}
+
+func (s *encryptedStore) List() ([]string, error) {
+	return s.store.List()
+}
+
+func encrypt(key []byte, entry *Entry) (string, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+
+	plaintext, err := json.Marshal(entry)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	sealed := aead.Seal(nonce, nonce, plaintext, []byte(entry.Namespace))
+	return encryptedVersion + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func decrypt(key []byte, namespace, ciphertext string) (*Entry, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.HasPrefix(ciphertext, encryptedVersion) {
+		return nil, ErrDecrypt
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(ciphertext, encryptedVersion))
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+
+	nonce, sealed := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, sealed, []byte(namespace))
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+
+	entry := &Entry{}
+	if err = json.Unmarshal(plaintext, entry); err != nil {
+		return nil, err
+	}
+	if entry.Namespace != namespace {
+		return nil, ErrDecrypt
+	}
+	return entry, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if !validKeyLength(len(key)) {
+		return nil, ErrInvalidKey
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,141 @@
+package backup
+
+import (
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sixgoatsh/agollo/core/config"
+)
+
+var (
+	oldKey = []byte("agollo-backup-encryption-key-old")
+	newKey = []byte("fedcba9876543210fedcba9876543210")
+)
+
+func TestEncryptedStore(t *testing.T) {
+	f, err := ioutil.TempFile("", "backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	conf := config.Configurations{"db.password": "secret"}
+
+	store := NewEncryptedStore(NewFileStore(f.Name()), StaticKey(oldKey))
+	assert.Nil(t, store.Save("application", "r1", conf))
+
+	// 备份文件中不包含明文
+	data, err := ioutil.ReadFile(f.Name())
+	assert.Nil(t, err)
+	assert.False(t, strings.Contains(string(data), "secret"))
+
+	entry, err := store.Load("application")
+	assert.Nil(t, err)
+	assert.Equal(t, conf, entry.Configurations)
+
+	// key错误时返回ErrDecrypt
+	_, err = NewEncryptedStore(NewFileStore(f.Name()), StaticKey(newKey)).Load("application")
+	assert.True(t, errors.Is(err, ErrDecrypt))
+
+	// 使用旧key解密后用新key重新加密
+	rotated := NewEncryptedStore(NewFileStore(f.Name()), StaticKey(newKey, oldKey))
+	entry, err = rotated.Load("application")
+	assert.Nil(t, err)
+	assert.Equal(t, conf, entry.Configurations)
+
+	entry, err = NewEncryptedStore(NewFileStore(f.Name()), StaticKey(newKey)).Load("application")
+	assert.Nil(t, err)
+	assert.Equal(t, conf, entry.Configurations)
+
+	// 不允许替换为其他namespace的备份
+	inner := NewMemoryStore()
+	store = NewEncryptedStore(inner, StaticKey(newKey))
+	assert.Nil(t, store.Save("redis", "", conf))
+	e, _ := inner.Load("redis")
+	assert.Nil(t, inner.Save("application", "", e.Configurations))
+	_, err = store.Load("application")
+	assert.True(t, errors.Is(err, ErrDecrypt))
+
+	// release key及获取时间同样加密，修改内部Store中的元数据不影响读取结果
+	fetchedAt := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.Nil(t, store.(EntrySaver).SaveEntry(&Entry{
+		Namespace:      "application",
+		ReleaseKey:     "r1",
+		Configurations: conf,
+		NotificationID: 7,
+		FetchedAt:      fetchedAt,
+	}))
+	e, _ = inner.Load("application")
+	assert.Equal(t, "", e.ReleaseKey)
+	assert.True(t, e.FetchedAt.IsZero())
+	e.ReleaseKey = "r2"
+	e.FetchedAt = time.Now()
+	assert.Nil(t, SaveEntry(inner, e))
+	entry, err = store.Load("application")
+	assert.Nil(t, err)
+	assert.Equal(t, "r1", entry.ReleaseKey)
+	assert.Equal(t, 7, entry.NotificationID)
+	assert.True(t, fetchedAt.Equal(entry.FetchedAt))
+}
+
+func TestEncryptedStoreMigratesPlaintext(t *testing.T) {
+	inner := NewMemoryStore()
+	conf := config.Configurations{"db.password": "secret"}
+	assert.Nil(t, inner.Save("application", "r1", conf))
+
+	// 默认拒绝明文备份
+	_, err := NewEncryptedStore(inner, StaticKey(newKey)).Load("application")
+	assert.True(t, errors.Is(err, ErrPlaintext))
+
+	store := NewEncryptedStore(inner, StaticKey(newKey), AllowPlaintextMigration())
+	entry, err := store.Load("application")
+	assert.Nil(t, err)
+	assert.Equal(t, conf, entry.Configurations)
+
+	assert.Equal(t, "r1", entry.ReleaseKey)
+
+	entry, err = inner.Load("application")
+	assert.Nil(t, err)
+	assert.NotContains(t, entry.Configurations, "db.password")
+	assert.Equal(t, "", entry.ReleaseKey)
+}
+
+func TestKeyProvider(t *testing.T) {
+	os.Setenv("AGOLLO_TEST_KEY", base64.StdEncoding.EncodeToString(newKey))
+	os.Setenv("AGOLLO_TEST_OLD_KEY", string(oldKey))
+	defer os.Unsetenv("AGOLLO_TEST_KEY")
+	defer os.Unsetenv("AGOLLO_TEST_OLD_KEY")
+
+	current, previous, err := EnvKey("AGOLLO_TEST_KEY", "AGOLLO_TEST_OLD_KEY").Keys()
+	assert.Nil(t, err)
+	assert.Equal(t, newKey, current)
+	assert.Equal(t, [][]byte{oldKey}, previous)
+
+	_, _, err = EnvKey("AGOLLO_TEST_MISSING_KEY").Keys()
+	assert.NotNil(t, err)
+
+	f, err := ioutil.TempFile("", "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("00112233445566778899aabbccddeeff\n")
+	f.Close()
+
+	current, _, err = FileKey(f.Name()).Keys()
+	assert.Nil(t, err)
+	assert.Len(t, current, 16)
+
+	_, err = NewEncryptedStore(NewMemoryStore(), StaticKey([]byte("short"))).Load("application")
+	assert.Equal(t, ErrNotFound, err)
+	err = NewEncryptedStore(NewMemoryStore(), StaticKey([]byte("short"))).Save("application", "", nil)
+	assert.Equal(t, ErrInvalidKey, err)
+}
@@ -0,0 +1,170 @@
+package backup
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/sixgoatsh/agollo/core/config"
+)
+
+// fileMetaKey 元数据保存在该key下，旧版本的客户端会将其视为一个普通的namespace
+const fileMetaKey = "__agollo_meta__"
+
+// fileStore 所有namespace保存在同一个文件中，格式与旧版本的.goApollo备份文件兼容，
+// 即 {"namespace": {"key": "value"}}，release key等元数据保存在fileMetaKey下
+type fileStore struct {
+	filename string
+
+	mu      sync.Mutex
+	loaded  bool
+	entries map[string]*Entry
+}
+
+// NewFileStore 兼容旧版本的单文件备份，options.BackupFile的默认实现
+func NewFileStore(filename string) Store {
+	return &fileStore{filename: filename}
+}
+
+func (s *fileStore) Save(namespace, releaseKey string, conf config.Configurations) error {
+	return s.SaveEntry(&Entry{
+		Namespace:      namespace,
+		ReleaseKey:     releaseKey,
+		Configurations: conf,
+	})
+}
+
+func (s *fileStore) SaveEntry(entry *Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		if !errors.Is(err, ErrCorrupt) {
+			// 无法读取的文件不能覆盖，否则会丢失其他namespace的备份
+			return err
+		}
+		// 文件损坏时移动到.bak后重新备份，保留损坏的文件用于排查
+		if err = os.Rename(s.filename, s.filename+".bak"); err != nil {
+			return err
+		}
+		s.entries = map[string]*Entry{}
+		s.loaded = true
+	}
+	e := *entry
+	s.entries[entry.Namespace] = &e
+
+	data, err := s.marshal()
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(s.filename, data, 0666)
+}
+
+func (s *fileStore) Load(namespace string) (*Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+
+	entry, ok := s.entries[namespace]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	e := *entry
+	return &e, nil
+}
+
+func (s *fileStore) List() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+
+	namespaces := make([]string, 0, len(s.entries))
+	for namespace := range s.entries {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+	return namespaces, nil
+}
+
+func (s *fileStore) marshal() ([]byte, error) {
+	data := make(map[string]interface{}, len(s.entries)+1)
+	metas := make(map[string]Entry, len(s.entries))
+	for namespace, entry := range s.entries {
+		data[namespace] = entry.Configurations
+
+		meta := *entry
+		meta.Configurations = nil
+		metas[namespace] = meta
+	}
+	data[fileMetaKey] = metas
+
+	return json.Marshal(data)
+}
+
+// load 仅在第一次访问时读取文件，之后以内存中的数据为准
+func (s *fileStore) load() error {
+	if s.loaded {
+		return nil
+	}
+	s.entries = map[string]*Entry{}
+
+	data, err := ioutil.ReadFile(s.filename)
+	if os.IsNotExist(err) {
+		s.loaded = true
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		// 空文件等同于没有备份，例如预先创建的备份文件
+		s.loaded = true
+		return nil
+	}
+
+	var raw map[string]json.RawMessage
+	if err = json.Unmarshal(data, &raw); err != nil {
+		return s.corrupt(err)
+	}
+
+	var metas map[string]Entry
+	if meta, ok := raw[fileMetaKey]; ok {
+		if err = json.Unmarshal(meta, &metas); err != nil {
+			return s.corrupt(err)
+		}
+		delete(raw, fileMetaKey)
+	}
+
+	entries := make(map[string]*Entry, len(raw))
+	for namespace, val := range raw {
+		var conf config.Configurations
+		if err = json.Unmarshal(val, &conf); err != nil {
+			return s.corrupt(err)
+		}
+
+		entry := metas[namespace]
+		entry.Namespace = namespace
+		entry.Configurations = conf
+		entries[namespace] = &entry
+	}
+
+	s.entries = entries
+	s.loaded = true
+	return nil
+}
+
+func (s *fileStore) corrupt(err error) error {
+	return fmt.Errorf("%w: %s: %v", ErrCorrupt, s.filename, err)
+}
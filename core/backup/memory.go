@@ -0,0 +1,90 @@
+package backup
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/sixgoatsh/agollo/core/config"
+)
+
+// memoryStore 仅保存在内存中，用于测试
+type memoryStore struct {
+	mu      sync.RWMutex
+	entries map[string]Entry
+}
+
+func NewMemoryStore() Store {
+	return &memoryStore{entries: map[string]Entry{}}
+}
+
+func (s *memoryStore) Save(namespace, releaseKey string, conf config.Configurations) error {
+	return s.SaveEntry(&Entry{
+		Namespace:      namespace,
+		ReleaseKey:     releaseKey,
+		Configurations: conf,
+	})
+}
+
+func (s *memoryStore) SaveEntry(entry *Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e := *entry
+	e.Configurations = copyConfigurations(entry.Configurations)
+	s.entries[entry.Namespace] = e
+	return nil
+}
+
+func (s *memoryStore) Load(namespace string) (*Entry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entry, ok := s.entries[namespace]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	entry.Configurations = copyConfigurations(entry.Configurations)
+	return &entry, nil
+}
+
+func (s *memoryStore) List() ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	namespaces := make([]string, 0, len(s.entries))
+	for namespace := range s.entries {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+	return namespaces, nil
+}
+
+// nopStore 不做任何备份
+type nopStore struct{}
+
+func NewNopStore() Store {
+	return nopStore{}
+}
+
+func (nopStore) Save(string, string, config.Configurations) error {
+	return nil
+}
+
+func (nopStore) Load(string) (*Entry, error) {
+	return nil, ErrNotFound
+}
+
+func (nopStore) List() ([]string, error) {
+	return nil, nil
+}
+
+func copyConfigurations(conf config.Configurations) config.Configurations {
+	if conf == nil {
+		return nil
+	}
+	c := make(config.Configurations, len(conf))
+	for k, v := range conf {
+		c[k] = v
+	}
+	return c
+}
@@ -0,0 +1,52 @@
+package backup
+
+import (
+	"strings"
+
+	"github.com/sixgoatsh/agollo/core/config"
+)
+
+// prefixStore 在namespace前加上前缀后保存到内部的Store，多个appID及cluster可以共用同一个Store
+type prefixStore struct {
+	store  Store
+	prefix string
+}
+
+// NewPrefixStore 保存时在namespace前加上prefix，List仅返回带有prefix的namespace，返回时去掉prefix
+func NewPrefixStore(store Store, prefix string) Store {
+	return &prefixStore{store: store, prefix: prefix}
+}
+
+func (s *prefixStore) Save(namespace, releaseKey string, conf config.Configurations) error {
+	return s.store.Save(s.prefix+namespace, releaseKey, conf)
+}
+
+func (s *prefixStore) SaveEntry(entry *Entry) error {
+	e := *entry
+	e.Namespace = s.prefix + entry.Namespace
+	return SaveEntry(s.store, &e)
+}
+
+func (s *prefixStore) Load(namespace string) (*Entry, error) {
+	entry, err := s.store.Load(s.prefix + namespace)
+	if err != nil {
+		return nil, err
+	}
+	entry.Namespace = namespace
+	return entry, nil
+}
+
+func (s *prefixStore) List() ([]string, error) {
+	all, err := s.store.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var namespaces []string
+	for _, namespace := range all {
+		if strings.HasPrefix(namespace, s.prefix) {
+			namespaces = append(namespaces, strings.TrimPrefix(namespace, s.prefix))
+		}
+	}
+	return namespaces, nil
+}
@@ -1,7 +1,7 @@
 package balancer
 
 import (
-	"sync"
+	"context"
 	"time"
 
 	"github.com/sixgoatsh/agollo/core/client"
@@ -18,30 +18,33 @@ type autoFetchBalancer struct {
 
 	logger log.Logger
 
-	mu sync.RWMutex
-	b  Balancer
+	b HealthBalancer
 
-	stopCh chan struct{}
+	ctx    context.Context // Stop时取消，中断正在进行的meta server请求
+	cancel context.CancelFunc
 }
 
 func NewAutoFetchBalancer(conf config.Config, metaServerClient client.IMetaServerClient,
-	refreshIntervalInSecond time.Duration, logger log.Logger) (Balancer, error) {
+	refreshIntervalInSecond time.Duration, logger log.Logger, opts ...HealthOption) (HealthBalancer, error) {
 
 	if refreshIntervalInSecond <= time.Duration(0) {
 		refreshIntervalInSecond = defaultRefreshIntervalInSecond
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
 	b := &autoFetchBalancer{
 		conf:              conf,
 		metaServerClient:  metaServerClient,
 		metaServerAddress: util.GetMetaServerAddress(conf.ConfigServerUrl), // Meta Server只是一个逻辑角色，在部署时和Config Service是在一个JVM进程中的，所以IP、端口和Config Service一致
 		logger:            logger,
-		stopCh:            make(chan struct{}),
-		b:                 NewRoundRobin([]string{conf.ConfigServerUrl}),
+		ctx:               ctx,
+		cancel:            cancel,
+		b:                 NewHealthBalancer([]string{conf.ConfigServerUrl}, opts...),
 	}
 
 	err := b.updateConfigServices()
 	if err != nil {
+		cancel()
 		return nil, err
 	}
 
@@ -51,7 +54,7 @@ func NewAutoFetchBalancer(conf config.Config, metaServerClient client.IMetaServe
 
 		for {
 			select {
-			case <-b.stopCh:
+			case <-b.ctx.Done():
 				return
 			case <-ticker.C:
 				_ = b.updateConfigServices()
@@ -73,17 +76,14 @@ func (b *autoFetchBalancer) updateConfigServices() error {
 		// check whether /services/config is accessible
 		conf := b.conf
 		conf.ConfigServerUrl = url
-		status, _, err := b.metaServerClient.GetConfigServers(conf)
+		status, _, err := b.metaServerClient.GetConfigServersCtx(b.ctx, conf)
 		if err != nil {
 			continue
 		}
 
-		// select the first available meta server
-		// https://github.com/ctripcorp/apollo/blob/7545bd3cd7d4b996d7cda50f53cd4aa8b045a2bb/apollo-core/src/main/java/com/ctrip/framework/apollo/core/MetaDomainConsts.java#L166
-		// 这里这段逻辑是参考java客户端，直接选了第一个可用的meta server
+		// 仅保留可访问的config server，不可访问的由HealthBalancer在后续请求中剔除
 		if 200 <= status && status <= 399 {
 			urls = append(urls, url)
-			break
 		}
 	}
 
@@ -91,9 +91,7 @@ func (b *autoFetchBalancer) updateConfigServices() error {
 		return nil
 	}
 
-	b.mu.Lock()
-	b.b = NewRoundRobin(css)
-	b.mu.Unlock()
+	b.b.Update(urls)
 
 	return nil
 }
@@ -101,14 +99,10 @@ func (b *autoFetchBalancer) updateConfigServices() error {
 func (b *autoFetchBalancer) getConfigServices() ([]string, error) {
 	conf := b.conf
 	conf.ConfigServerUrl = b.metaServerAddress
-	_, css, err := b.metaServerClient.GetConfigServers(conf)
+	_, css, err := b.metaServerClient.GetConfigServersCtx(b.ctx, conf)
 	if err != nil {
-		b.logger.Log(
-			"[GoApollo]", "",
-			"AppID", conf.AppID,
-			"MetaServerAddress", b.metaServerAddress,
-			"Error", err,
-		)
+		b.logger.Warn("fetch config servers failed",
+			"app_id", conf.AppID, "meta_server", b.metaServerAddress, "error", err)
 		return nil, err
 	}
 
@@ -121,11 +115,21 @@ func (b *autoFetchBalancer) getConfigServices() ([]string, error) {
 }
 
 func (b *autoFetchBalancer) Select() (string, error) {
-	b.mu.RLock()
-	defer b.mu.RUnlock()
 	return b.b.Select()
 }
 
+func (b *autoFetchBalancer) Report(url string, err error) {
+	b.b.Report(url, err)
+}
+
+func (b *autoFetchBalancer) Update(ss []string) {
+	b.b.Update(ss)
+}
+
+func (b *autoFetchBalancer) Endpoints() []EndpointStatus {
+	return b.b.Endpoints()
+}
+
 func (b *autoFetchBalancer) Stop() {
-	close(b.stopCh)
+	b.cancel()
 }
@@ -30,7 +30,7 @@ func NewBalancer(conf config.Config, enableSLB bool, refreshIntervalInSecond tim
 			return nil, err
 		}
 	} else {
-		b = NewRoundRobin(configServerURLs)
+		b = NewHealthBalancer(configServerURLs)
 	}
 
 	return b, nil
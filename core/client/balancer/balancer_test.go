@@ -1,6 +1,7 @@
 package balancer
 
 import (
+	"errors"
 	"sync"
 	"testing"
 	"time"
@@ -8,6 +9,7 @@ import (
 	"gopkg.in/go-playground/assert.v1"
 
 	"github.com/sixgoatsh/agollo/core/client"
+	"github.com/sixgoatsh/agollo/core/config"
 	"github.com/sixgoatsh/agollo/core/mock"
 	"github.com/sixgoatsh/agollo/pkg/log"
 )
@@ -23,37 +25,50 @@ func TestAutoFetchBalancer(t *testing.T) {
 		},
 	}
 
-	var wg sync.WaitGroup
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	// 在修改expected的goroutine启动前读取，避免数据竞争
+	firstURL := expected[0].HomePageURL
+	wg.Add(1)
 	go func() {
-		<-time.After(refreshIntervalInSecond)
+		<-time.After(refreshIntervalInSecond / 2)
 
+		mu.Lock()
 		expected = append(expected, client.ConfigServerResp{
 			AppName:     "APOLLO-CONFIGSERVICE",
 			InstanceID:  "localhost:apollo-configservice:8081",
 			HomePageURL: "http://127.0.0.1:8081",
 		})
+		mu.Unlock()
 
 		wg.Done()
 	}()
 
 	metaServerClient := &mock.MetaServerClient{
-		ConfigServers: func(metaServerURL, appID string) (int, []client.ConfigServerResp, error) {
-			return 200, expected, nil
+		ConfigServers: func(config.Config) (int, []client.ConfigServerResp, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			return 200, append([]client.ConfigServerResp(nil), expected...), nil
 		},
 	}
 
-	b, err := NewAutoFetchBalancer("", "", metaServerClient, refreshIntervalInSecond, log.NewLogger())
+	b, err := NewAutoFetchBalancer(config.DefaultConfig("", ""), metaServerClient, refreshIntervalInSecond, log.NewLogger())
 	if err != nil {
 		t.Fatal(err)
 	}
+	defer b.Stop()
 
 	actual, err := b.Select()
 	if err != nil {
 		t.Fatal(err)
 	}
-	assert.Equal(t, expected[0].HomePageURL, actual)
+	assert.Equal(t, firstURL, actual)
 
 	wg.Wait()
+	// 等待下一次刷新ConfigServer列表
+	time.Sleep(refreshIntervalInSecond)
 
 	for i := 0; i < 10; i++ {
 		actual, err := b.Select()
@@ -82,3 +97,104 @@ func TestRoundRobin(t *testing.T) {
 	}
 
 }
+
+func TestAutoFetchBalancerSkipsUnreachable(t *testing.T) {
+	css := []client.ConfigServerResp{
+		{HomePageURL: "http://127.0.0.1:8080"},
+		{HomePageURL: "http://127.0.0.1:8081"},
+		{HomePageURL: "http://127.0.0.1:8082"},
+	}
+
+	metaServerClient := &mock.MetaServerClient{
+		ConfigServers: func(conf config.Config) (int, []client.ConfigServerResp, error) {
+			if conf.ConfigServerUrl == "http://127.0.0.1:8081" {
+				return 0, nil, errors.New("connection refused")
+			}
+			return 200, css, nil
+		},
+	}
+
+	b, err := NewAutoFetchBalancer(config.DefaultConfig("", ""), metaServerClient, time.Minute, log.NewLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Stop()
+
+	expected := []string{"http://127.0.0.1:8080", "http://127.0.0.1:8082"}
+	for i := 0; i < 10; i++ {
+		actual, err := b.Select()
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, expected[i%len(expected)], actual)
+	}
+
+	// 请求失败后被剔除
+	b.Report("http://127.0.0.1:8080", errors.New("status 500"))
+	for i := 0; i < 5; i++ {
+		actual, _ := b.Select()
+		assert.Equal(t, "http://127.0.0.1:8082", actual)
+	}
+}
+
+func TestHealthBalancer(t *testing.T) {
+	servers := []string{
+		"http://127.0.0.1:8080/",
+		"http://127.0.0.1:8081/",
+	}
+
+	now := time.Now()
+	lb := NewHealthBalancer(servers, FailureThreshold(2), EjectionDuration(time.Second, 3*time.Second)).(*healthBalancer)
+	lb.now = func() time.Time { return now }
+
+	selectN := func(n int) map[string]int {
+		selected := map[string]int{}
+		for i := 0; i < n; i++ {
+			url, err := lb.Select()
+			if err != nil {
+				t.Fatal(err)
+			}
+			selected[url]++
+		}
+		return selected
+	}
+
+	// 未达到阈值时不剔除
+	lb.Report(servers[0], errors.New("timeout"))
+	assert.Equal(t, 2, len(selectN(4)))
+
+	// 达到阈值后剔除
+	lb.Report(servers[0], errors.New("timeout"))
+	assert.Equal(t, map[string]int{servers[1]: 4}, selectN(4))
+	assert.Equal(t, false, lb.Endpoints()[0].Healthy)
+	assert.Equal(t, "timeout", lb.Endpoints()[0].LastError)
+
+	// 剔除时间到期后重新加入
+	now = now.Add(time.Second)
+	assert.Equal(t, 2, len(selectN(4)))
+
+	// 再次失败时剔除时间翻倍
+	lb.Report(servers[0], errors.New("timeout"))
+	lb.Report(servers[0], errors.New("timeout"))
+	assert.Equal(t, now.Add(2*time.Second), lb.Endpoints()[0].EjectedUntil)
+
+	// 不超过最大剔除时间
+	now = now.Add(2 * time.Second)
+	lb.Report(servers[0], errors.New("timeout"))
+	lb.Report(servers[0], errors.New("timeout"))
+	assert.Equal(t, now.Add(3*time.Second), lb.Endpoints()[0].EjectedUntil)
+
+	// 全部被剔除时退化为全部列表
+	lb.Report(servers[1], errors.New("timeout"))
+	lb.Report(servers[1], errors.New("timeout"))
+	assert.Equal(t, 2, len(selectN(4)))
+
+	// 成功后恢复
+	lb.Report(servers[0], nil)
+	assert.Equal(t, map[string]int{servers[0]: 4}, selectN(4))
+	assert.Equal(t, 0, lb.Endpoints()[0].Ejections)
+
+	// 更新列表时保留健康状态
+	lb.Update([]string{servers[1], "http://127.0.0.1:8082/"})
+	assert.Equal(t, map[string]int{"http://127.0.0.1:8082/": 4}, selectN(4))
+}
@@ -0,0 +1,207 @@
+package balancer
+
+import (
+	"sync"
+	"time"
+)
+
+var (
+	defaultFailureThreshold = 1
+	defaultEjectionDuration = 10 * time.Second
+	defaultMaxEjection      = 5 * time.Minute
+)
+
+// Reporter 上报每次请求ConfigServer的结果，健康感知的Balancer据此剔除异常的ConfigServer
+type Reporter interface {
+	Report(url string, err error)
+}
+
+// HealthBalancer 根据请求结果剔除异常的ConfigServer，剔除时间到期后重新加入，
+// 再次失败时剔除时间翻倍，所有ConfigServer都被剔除时退化为在全部列表中轮询
+type HealthBalancer interface {
+	Balancer
+	Reporter
+	// Update 更新ConfigServer列表，保留仍在列表中的ConfigServer的健康状态
+	Update(ss []string)
+	// Endpoints 返回所有ConfigServer的健康状态
+	Endpoints() []EndpointStatus
+}
+
+type EndpointStatus struct {
+	URL                 string    `json:"url"`
+	Healthy             bool      `json:"healthy"`
+	ConsecutiveFailures int       `json:"consecutiveFailures"`
+	Ejections           int       `json:"ejections"`
+	EjectedUntil        time.Time `json:"ejectedUntil,omitempty"`
+	LastError           string    `json:"lastError,omitempty"`
+}
+
+type endpoint struct {
+	url          string
+	failures     int // 连续失败次数
+	ejections    int // 连续被剔除的次数，成功后清零
+	ejectedUntil time.Time
+	lastErr      error
+}
+
+type healthBalancer struct {
+	failureThreshold int
+	ejectionDuration time.Duration
+	maxEjection      time.Duration
+	now              func() time.Time
+
+	mu        sync.Mutex
+	endpoints []*endpoint
+	c         uint64
+}
+
+type HealthOption func(*healthBalancer)
+
+// FailureThreshold 连续失败多少次后剔除，默认：1
+func FailureThreshold(n int) HealthOption {
+	return func(b *healthBalancer) {
+		b.failureThreshold = n
+	}
+}
+
+// EjectionDuration 首次剔除的时间及最大剔除时间，默认：10s、5m
+func EjectionDuration(base, max time.Duration) HealthOption {
+	return func(b *healthBalancer) {
+		b.ejectionDuration = base
+		b.maxEjection = max
+	}
+}
+
+func NewHealthBalancer(ss []string, opts ...HealthOption) HealthBalancer {
+	b := &healthBalancer{
+		failureThreshold: defaultFailureThreshold,
+		ejectionDuration: defaultEjectionDuration,
+		maxEjection:      defaultMaxEjection,
+		now:              time.Now,
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	if b.failureThreshold <= 0 {
+		b.failureThreshold = defaultFailureThreshold
+	}
+
+	b.Update(ss)
+	return b
+}
+
+func (b *healthBalancer) Select() (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.endpoints) == 0 {
+		return "", ErrNoConfigServerAvailable
+	}
+
+	now := b.now()
+	var healthy []*endpoint
+	for _, e := range b.endpoints {
+		if !now.Before(e.ejectedUntil) {
+			healthy = append(healthy, e)
+		}
+	}
+
+	// 全部被剔除时，退化为在全部列表中轮询
+	if len(healthy) == 0 {
+		healthy = b.endpoints
+	}
+
+	idx := b.c % uint64(len(healthy))
+	b.c++
+	return healthy[idx].url, nil
+}
+
+func (b *healthBalancer) Report(url string, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	e := b.find(url)
+	if e == nil {
+		return
+	}
+
+	if err == nil {
+		e.failures = 0
+		e.ejections = 0
+		e.ejectedUntil = time.Time{}
+		e.lastErr = nil
+		return
+	}
+
+	e.failures++
+	e.lastErr = err
+	if e.failures < b.failureThreshold {
+		return
+	}
+
+	// 每次被剔除的时间翻倍，直到maxEjection
+	e.failures = 0
+	e.ejections++
+	d := b.ejectionDuration << uint(e.ejections-1)
+	if d <= 0 || (b.maxEjection > 0 && d > b.maxEjection) {
+		d = b.maxEjection
+	}
+	e.ejectedUntil = b.now().Add(d)
+}
+
+func (b *healthBalancer) Update(ss []string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	endpoints := make([]*endpoint, 0, len(ss))
+	changed := len(ss) != len(b.endpoints)
+	for i, url := range ss {
+		e := b.find(url)
+		if e == nil {
+			e = &endpoint{url: url}
+		}
+		if !changed && b.endpoints[i].url != url {
+			changed = true
+		}
+		endpoints = append(endpoints, e)
+	}
+
+	b.endpoints = endpoints
+	if changed {
+		b.c = 0
+	}
+}
+
+func (b *healthBalancer) Endpoints() []EndpointStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	statuses := make([]EndpointStatus, 0, len(b.endpoints))
+	for _, e := range b.endpoints {
+		status := EndpointStatus{
+			URL:                 e.url,
+			Healthy:             !now.Before(e.ejectedUntil),
+			ConsecutiveFailures: e.failures,
+			Ejections:           e.ejections,
+			EjectedUntil:        e.ejectedUntil,
+		}
+		if e.lastErr != nil {
+			status.LastError = e.lastErr.Error()
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+func (b *healthBalancer) find(url string) *endpoint {
+	for _, e := range b.endpoints {
+		if e.url == url {
+			return e
+		}
+	}
+	return nil
+}
+
+// Stop 节点仅在Select及Report时被动剔除和恢复，没有探测的goroutine需要停止
+func (b *healthBalancer) Stop() {}
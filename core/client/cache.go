@@ -1,7 +1,9 @@
 package client
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"net/url"
 
 	"github.com/sixgoatsh/agollo/core/auth"
@@ -11,14 +13,29 @@ import (
 	"github.com/sixgoatsh/agollo/pkg/util/uri"
 )
 
+// StatusError apollo返回了非预期的HTTP Status
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("apollo: unexpected response status %d", e.StatusCode)
+}
+
 type ICacheClient interface {
 	GetConfigsFromCache(config.Config) (conf *config.Configurations, err error)
+	GetConfigsFromCacheCtx(context.Context, config.Config) (conf *config.Configurations, err error)
 }
 
 type CacheClient struct {
+	RestClient *rest.Client // 为空时使用rest.DefaultClient
 }
 
 func (c *CacheClient) GetConfigsFromCache(clientConf config.Config) (conf *config.Configurations, err error) {
+	return c.GetConfigsFromCacheCtx(context.Background(), clientConf)
+}
+
+func (c *CacheClient) GetConfigsFromCacheCtx(ctx context.Context, clientConf config.Config) (conf *config.Configurations, err error) {
 	requestURI := fmt.Sprintf("/configfiles/json/%s/%s/%s?ip=%s",
 		url.QueryEscape(clientConf.AppID),
 		url.QueryEscape(clientConf.ClusterName),
@@ -28,6 +45,13 @@ func (c *CacheClient) GetConfigsFromCache(clientConf config.Config) (conf *confi
 	apiURL := fmt.Sprintf("%s%s", uri.NormalizeURL(clientConf.ConfigServerUrl), requestURI)
 	headers := auth.HttpHeader(clientConf.AccessKey, clientConf.AppID, requestURI)
 	conf = new(config.Configurations)
-	_, err = rest.Do("GET", apiURL, headers, conf)
-	return
+	status, err := restClient(c.RestClient).Do(ctx, rest.EndpointConfig, "GET", apiURL, headers, conf)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		// 该接口没有返回HTTP Status，非200时通过StatusError返回
+		return nil, &StatusError{StatusCode: status}
+	}
+	return conf, nil
 }
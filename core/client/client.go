@@ -1,19 +1,27 @@
 package client
 
 import (
+	"context"
+
 	"github.com/sixgoatsh/agollo/core/config"
+	"github.com/sixgoatsh/agollo/pkg/rest"
 )
 
+// IApolloClient 每个接口都有对应的Ctx版本，ctx被取消或者超时时会中断正在进行的HTTP请求
 type IApolloClient interface {
-	GetNotifications(conf config.Config, result []config.Notification) (status int, err error)
+	GetNotifications(conf config.Config) (status int, notifications []config.Notification, err error)
+	GetNotificationsCtx(ctx context.Context, conf config.Config) (status int, notifications []config.Notification, err error)
 
 	// 该接口会直接从数据库中获取配置，可以配合配置推送通知实现实时更新配置。
 	GetConfigsFromNonCache(conf config.Config, opts ...NotificationsOption) (int, *NonCacheResp, error)
+	GetConfigsFromNonCacheCtx(ctx context.Context, conf config.Config, opts ...NotificationsOption) (int, *NonCacheResp, error)
 	// 该接口会从缓存中获取配置，适合频率较高的配置拉取请求，如简单的每30秒轮询一次配置。
 	GetConfigsFromCache(config.Config) (conf *config.Configurations, err error)
+	GetConfigsFromCacheCtx(context.Context, config.Config) (conf *config.Configurations, err error)
 
 	// 该接口从MetaServer获取ConfigServer列表
 	GetConfigServers(config.Config) (int, []ConfigServerResp, error)
+	GetConfigServersCtx(context.Context, config.Config) (int, []ConfigServerResp, error)
 }
 
 type ApolloClient struct {
@@ -33,10 +41,22 @@ func NewApolloClient(metaServerClient IMetaServerClient, nonCacheClient INonCach
 }
 
 func New() IApolloClient {
+	return NewWithRestClient(nil)
+}
+
+// NewWithRestClient 所有接口使用同一个rest.Client发送请求，restClient为空时使用rest.DefaultClient
+func NewWithRestClient(restClient *rest.Client) IApolloClient {
 	return &ApolloClient{
-		IMetaServerClient:   &MetaServerClient{},
-		INonCacheClient:     &NonCacheClient{},
-		ICacheClient:        &CacheClient{},
-		INotificationClient: &NotificationClient{},
+		IMetaServerClient:   &MetaServerClient{RestClient: restClient},
+		INonCacheClient:     &NonCacheClient{RestClient: restClient},
+		ICacheClient:        &CacheClient{RestClient: restClient},
+		INotificationClient: &NotificationClient{RestClient: restClient},
+	}
+}
+
+func restClient(c *rest.Client) *rest.Client {
+	if c == nil {
+		return rest.DefaultClient
 	}
+	return c
 }
@@ -0,0 +1,260 @@
+package client
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sixgoatsh/agollo/core/config"
+	"github.com/sixgoatsh/agollo/core/util"
+)
+
+var (
+	defaultLocalPollInterval = time.Second
+	defaultLocalHoldTimeout  = 60 * time.Second
+
+	// localFormats 未指定格式的namespace按顺序查找的文件后缀
+	localFormats = []config.Format{config.FormatProperties, config.FormatJSON, config.FormatYAML, config.FormatYML}
+	// localRawFormats apollo中非properties格式的namespace，配置内容整体保存在content中
+	localRawFormats = map[string]bool{"json": true, "yaml": true, "yml": true, "xml": true, "txt": true}
+)
+
+// LocalClient 从本地目录读取配置，不连接apollo，用于本地开发及CI
+//
+// namespace与文件的对应关系：
+//   - application、application.properties：依次查找application.properties、.json、.yaml、.yml，解析为key/value
+//   - config.json、config.yaml、config.xml、config.txt：与apollo一致，文件内容保存在content中
+//
+// 通知接口定期检查文件内容，文件变更后notificationID递增，从而触发goApollo的Watch事件
+type LocalClient struct {
+	dir          string
+	pollInterval time.Duration
+	holdTimeout  time.Duration
+
+	mu       sync.Mutex
+	versions map[string]*localVersion // key: namespace
+}
+
+type localVersion struct {
+	releaseKey     string
+	notificationID int
+}
+
+type LocalOption func(*LocalClient)
+
+// LocalPollInterval 检查文件变更的间隔，默认：1s
+func LocalPollInterval(d time.Duration) LocalOption {
+	return func(c *LocalClient) {
+		c.pollInterval = d
+	}
+}
+
+// LocalHoldTimeout 通知接口在没有变更时的等待时间，与apollo一致，默认：60s
+func LocalHoldTimeout(d time.Duration) LocalOption {
+	return func(c *LocalClient) {
+		c.holdTimeout = d
+	}
+}
+
+func NewLocalClient(dir string, opts ...LocalOption) *LocalClient {
+	c := &LocalClient{
+		dir:          dir,
+		pollInterval: defaultLocalPollInterval,
+		holdTimeout:  defaultLocalHoldTimeout,
+		versions:     map[string]*localVersion{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *LocalClient) GetNotifications(conf config.Config) (int, []config.Notification, error) {
+	return c.GetNotificationsCtx(context.Background(), conf)
+}
+
+// GetNotificationsCtx 与apollo一致，有变更时立即返回200，否则hold直到文件变更或超时后返回304
+func (c *LocalClient) GetNotificationsCtx(ctx context.Context, conf config.Config) (int, []config.Notification, error) {
+	timeout := time.NewTimer(c.holdTimeout)
+	defer timeout.Stop()
+
+	ticker := time.NewTicker(c.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		var notifications []config.Notification
+		for _, n := range conf.Notifications {
+			version, err := c.check(n.NamespaceName)
+			if err != nil {
+				// 不存在的namespace与apollo一致，不返回通知
+				continue
+			}
+			if version.notificationID != n.NotificationID {
+				notifications = append(notifications, config.Notification{
+					NamespaceName:  n.NamespaceName,
+					NotificationID: version.notificationID,
+				})
+			}
+		}
+		if len(notifications) > 0 {
+			return http.StatusOK, notifications, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return 0, nil, ctx.Err()
+		case <-timeout.C:
+			return http.StatusNotModified, nil, nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *LocalClient) GetConfigsFromNonCache(conf config.Config, opts ...NotificationsOption) (int, *NonCacheResp, error) {
+	return c.GetConfigsFromNonCacheCtx(context.Background(), conf, opts...)
+}
+
+func (c *LocalClient) GetConfigsFromNonCacheCtx(ctx context.Context, conf config.Config, opts ...NotificationsOption) (int, *NonCacheResp, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, nil, err
+	}
+
+	var options = NotificationsOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	namespace := util.GetNamespace(conf.ConfigType, conf.NamespaceName)
+	configurations, releaseKey, err := c.load(namespace)
+	if os.IsNotExist(err) {
+		return http.StatusNotFound, nil, nil
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if options.ReleaseKey == releaseKey {
+		return http.StatusNotModified, nil, nil
+	}
+
+	return http.StatusOK, &NonCacheResp{
+		AppID:          conf.AppID,
+		Cluster:        conf.ClusterName,
+		NamespaceName:  namespace,
+		Configurations: configurations,
+		ReleaseKey:     releaseKey,
+	}, nil
+}
+
+func (c *LocalClient) GetConfigsFromCache(conf config.Config) (*config.Configurations, error) {
+	return c.GetConfigsFromCacheCtx(context.Background(), conf)
+}
+
+func (c *LocalClient) GetConfigsFromCacheCtx(ctx context.Context, conf config.Config) (*config.Configurations, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	configurations, _, err := c.load(util.GetNamespace(conf.ConfigType, conf.NamespaceName))
+	if os.IsNotExist(err) {
+		return nil, &StatusError{StatusCode: http.StatusNotFound}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &configurations, nil
+}
+
+// GetConfigServers 本地模式没有ConfigServer，返回请求中的ConfigServerUrl
+func (c *LocalClient) GetConfigServers(conf config.Config) (int, []ConfigServerResp, error) {
+	return c.GetConfigServersCtx(context.Background(), conf)
+}
+
+func (c *LocalClient) GetConfigServersCtx(ctx context.Context, conf config.Config) (int, []ConfigServerResp, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, []ConfigServerResp{{HomePageURL: conf.ConfigServerUrl}}, nil
+}
+
+// check 读取namespace当前的版本，内容变更时notificationID递增
+func (c *LocalClient) check(namespace string) (localVersion, error) {
+	_, releaseKey, err := c.load(namespace)
+	if err != nil {
+		return localVersion{}, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	v, ok := c.versions[namespace]
+	if !ok {
+		v = &localVersion{}
+		c.versions[namespace] = v
+	}
+	if v.releaseKey != releaseKey {
+		v.releaseKey = releaseKey
+		v.notificationID++
+	}
+	return *v, nil
+}
+
+// load 读取namespace对应的文件，release key为文件内容的sha1
+func (c *LocalClient) load(namespace string) (config.Configurations, string, error) {
+	filename, format, raw := c.lookup(namespace)
+	if filename == "" {
+		return nil, "", os.ErrNotExist
+	}
+
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, "", err
+	}
+
+	sum := sha1.Sum(content)
+	releaseKey := hex.EncodeToString(sum[:])
+
+	if raw {
+		return config.Configurations{"content": string(content)}, releaseKey, nil
+	}
+
+	configurations, err := config.Parse(format, content)
+	if err != nil {
+		return nil, "", err
+	}
+	return configurations, releaseKey, nil
+}
+
+// lookup 返回namespace对应的文件及格式，raw为true时文件内容不解析，直接保存在content中
+func (c *LocalClient) lookup(namespace string) (filename string, format config.Format, raw bool) {
+	ext := strings.TrimPrefix(path.Ext(namespace), ".")
+	if localRawFormats[ext] {
+		filename = filepath.Join(c.dir, namespace)
+		if !fileExists(filename) {
+			return "", "", false
+		}
+		return filename, config.Format(ext), true
+	}
+
+	name := strings.TrimSuffix(namespace, "."+string(config.FormatProperties))
+	for _, format := range localFormats {
+		filename = filepath.Join(c.dir, name+"."+string(format))
+		if fileExists(filename) {
+			return filename, format, false
+		}
+	}
+	return "", "", false
+}
+
+func fileExists(filename string) bool {
+	info, err := os.Stat(filename)
+	return err == nil && !info.IsDir()
+}
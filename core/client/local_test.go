@@ -0,0 +1,89 @@
+package client
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sixgoatsh/agollo/core/config"
+)
+
+func TestLocalClient(t *testing.T) {
+	dir, err := ioutil.TempDir("", "local")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(name, content string) {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("application.properties", "timeout=100\n")
+	write("db.yaml", "db:\n  host: 127.0.0.1\n")
+	write("redis.json", `{"host": "127.0.0.1"}`)
+
+	c := NewLocalClient(dir, LocalPollInterval(10*time.Millisecond), LocalHoldTimeout(100*time.Millisecond))
+	conf := config.DefaultConfig("", "test")
+
+	for namespace, expected := range map[string]config.Configurations{
+		"application":            {"timeout": "100"},
+		"application.properties": {"timeout": "100"},
+		"db":                     {"db.host": "127.0.0.1"},
+		"db.yaml":                {"content": "db:\n  host: 127.0.0.1\n"},
+		"redis.json":             {"content": `{"host": "127.0.0.1"}`},
+	} {
+		conf.NamespaceName = namespace
+		status, resp, err := c.GetConfigsFromNonCache(conf)
+		assert.Nil(t, err, namespace)
+		assert.Equal(t, http.StatusOK, status, namespace)
+		assert.Equal(t, expected, resp.Configurations, namespace)
+
+		status, _, err = c.GetConfigsFromNonCache(conf, ReleaseKey(resp.ReleaseKey))
+		assert.Nil(t, err, namespace)
+		assert.Equal(t, http.StatusNotModified, status, namespace)
+	}
+
+	conf.NamespaceName = "missing"
+	status, _, err := c.GetConfigsFromNonCache(conf)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusNotFound, status)
+
+	// 首次请求立即返回当前的notificationID
+	conf.Notifications = []config.Notification{{NamespaceName: "application", NotificationID: -1}}
+	status, notifications, err := c.GetNotifications(conf)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, []config.Notification{{NamespaceName: "application", NotificationID: 1}}, notifications)
+
+	// 没有变更时hold到超时
+	conf.Notifications = notifications
+	status, _, err = c.GetNotifications(conf)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusNotModified, status)
+
+	// 文件变更后返回新的notificationID
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		write("application.properties", "timeout=200\n")
+	}()
+	c.holdTimeout = time.Second
+	status, notifications, err = c.GetNotifications(conf)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, []config.Notification{{NamespaceName: "application", NotificationID: 2}}, notifications)
+
+	// ctx取消时立即返回
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	conf.Notifications = notifications
+	_, _, err = c.GetNotificationsCtx(ctx, conf)
+	assert.Equal(t, context.Canceled, err)
+}
@@ -1,6 +1,7 @@
 package client
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/sixgoatsh/agollo/core/auth"
@@ -11,9 +12,11 @@ import (
 
 type IMetaServerClient interface {
 	GetConfigServers(conf config.Config) (int, []ConfigServerResp, error)
+	GetConfigServersCtx(ctx context.Context, conf config.Config) (int, []ConfigServerResp, error)
 }
 
 type MetaServerClient struct {
+	RestClient *rest.Client // 为空时使用rest.DefaultClient
 }
 
 type ConfigServerResp struct {
@@ -23,10 +26,14 @@ type ConfigServerResp struct {
 }
 
 func (c *MetaServerClient) GetConfigServers(conf config.Config) (int, []ConfigServerResp, error) {
+	return c.GetConfigServersCtx(context.Background(), conf)
+}
+
+func (c *MetaServerClient) GetConfigServersCtx(ctx context.Context, conf config.Config) (int, []ConfigServerResp, error) {
 	requestURI := fmt.Sprintf("/services/config?id=%s&appId=%s", conf.IP, conf.AppID)
 	apiURL := fmt.Sprintf("%s%s", uri.NormalizeURL(conf.ConfigServerUrl), requestURI)
 	headers := auth.HttpHeader(conf.AccessKey, conf.AppID, requestURI)
 	var cfs []ConfigServerResp
-	status, err := rest.Do("GET", apiURL, headers, &cfs)
+	status, err := restClient(c.RestClient).Do(ctx, rest.EndpointMetaServer, "GET", apiURL, headers, &cfs)
 	return status, cfs, err
 }
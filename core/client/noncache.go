@@ -1,6 +1,7 @@
 package client
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 
@@ -13,9 +14,11 @@ import (
 
 type INonCacheClient interface {
 	GetConfigsFromNonCache(conf config.Config, opts ...NotificationsOption) (int, *NonCacheResp, error)
+	GetConfigsFromNonCacheCtx(ctx context.Context, conf config.Config, opts ...NotificationsOption) (int, *NonCacheResp, error)
 }
 
 type NonCacheClient struct {
+	RestClient *rest.Client // 为空时使用rest.DefaultClient
 }
 
 type NonCacheResp struct {
@@ -27,6 +30,10 @@ type NonCacheResp struct {
 }
 
 func (c *NonCacheClient) GetConfigsFromNonCache(conf config.Config, opts ...NotificationsOption) (status int, resp *NonCacheResp, err error) {
+	return c.GetConfigsFromNonCacheCtx(context.Background(), conf, opts...)
+}
+
+func (c *NonCacheClient) GetConfigsFromNonCacheCtx(ctx context.Context, conf config.Config, opts ...NotificationsOption) (status int, resp *NonCacheResp, err error) {
 	var options = NotificationsOptions{}
 	for _, opt := range opts {
 		opt(&options)
@@ -42,7 +49,7 @@ func (c *NonCacheClient) GetConfigsFromNonCache(conf config.Config, opts ...Noti
 	apiURL := fmt.Sprintf("%s%s", uri.NormalizeURL(conf.ConfigServerUrl), requestURI)
 	headers := auth.HttpHeader(conf.AccessKey, conf.AppID, requestURI)
 	resp = new(NonCacheResp)
-	status, err = rest.Do("GET", apiURL, headers, resp)
+	status, err = restClient(c.RestClient).Do(ctx, rest.EndpointConfig, "GET", apiURL, headers, resp)
 	return
 
 }
@@ -1,7 +1,10 @@
 package client
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"net/url"
 
 	"github.com/sixgoatsh/agollo/core/auth"
@@ -10,6 +13,11 @@ import (
 	"github.com/sixgoatsh/agollo/pkg/util/uri"
 )
 
+var (
+	// apollo在appId或cluster不存在时，notifications/v2接口会返回404
+	ErrNotificationsNotFound = errors.New("apollo: notifications not found")
+)
+
 type NotificationsOptions struct {
 	ReleaseKey string
 }
@@ -23,13 +31,24 @@ func ReleaseKey(releaseKey string) NotificationsOption {
 }
 
 type INotificationClient interface {
-	GetNotifications(conf config.Config,result []config.Notification) (status int, err error)
+	// HTTP Status: 200时，返回有更新的namespace及其最新的notificationID
+	// HTTP Status: 304时，请求的namespace在hold期间都没有更新，返回空的notifications
+	// HTTP Status: 404时，返回ErrNotificationsNotFound
+	// 其余的HTTP Status均返回error
+	GetNotifications(conf config.Config) (status int, notifications []config.Notification, err error)
+	// ctx被取消时会立即中断被hold住的长轮训请求
+	GetNotificationsCtx(ctx context.Context, conf config.Config) (status int, notifications []config.Notification, err error)
 }
 
 type NotificationClient struct {
+	RestClient *rest.Client // 为空时使用rest.DefaultClient
+}
+
+func (c *NotificationClient) GetNotifications(conf config.Config) (status int, notifications []config.Notification, err error) {
+	return c.GetNotificationsCtx(context.Background(), conf)
 }
 
-func (c *NotificationClient) GetNotifications(conf config.Config, result []config.Notification) (status int, err error) {
+func (c *NotificationClient) GetNotificationsCtx(ctx context.Context, conf config.Config) (status int, notifications []config.Notification, err error) {
 	requestURI := fmt.Sprintf("/notifications/v2?appId=%s&cluster=%s&notifications=%s",
 		url.QueryEscape(conf.AppID),
 		url.QueryEscape(conf.ClusterName),
@@ -38,6 +57,19 @@ func (c *NotificationClient) GetNotifications(conf config.Config, result []confi
 	apiURL := fmt.Sprintf("%s%s", uri.NormalizeURL(conf.ConfigServerUrl), requestURI)
 
 	headers := auth.HttpHeader(conf.AccessKey, conf.AppID, requestURI)
-	status, err = rest.Do("GET", apiURL, headers, &result)
-	return
+	status, err = restClient(c.RestClient).Do(ctx, rest.EndpointLongPoll, "GET", apiURL, headers, &notifications)
+	if err != nil {
+		return status, nil, err
+	}
+
+	switch status {
+	case http.StatusOK:
+		return status, notifications, nil
+	case http.StatusNotModified:
+		return status, nil, nil
+	case http.StatusNotFound:
+		return status, nil, ErrNotificationsNotFound
+	default:
+		return status, nil, fmt.Errorf("apollo: unexpected notifications response status %d", status)
+	}
 }
@@ -0,0 +1,82 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sixgoatsh/agollo/core/config"
+)
+
+func TestNotificationClient(t *testing.T) {
+	tests := []struct {
+		status        int
+		body          interface{}
+		expectedError error
+		expected      []config.Notification
+	}{
+		{
+			status: http.StatusOK,
+			body: []config.Notification{
+				{
+					NamespaceName:  "application",
+					NotificationID: 107,
+					Messages: &config.NotificationMessages{
+						Details: map[string]int64{"SampleApp+default+application": 107},
+					},
+				},
+			},
+			expected: []config.Notification{
+				{
+					NamespaceName:  "application",
+					NotificationID: 107,
+					Messages: &config.NotificationMessages{
+						Details: map[string]int64{"SampleApp+default+application": 107},
+					},
+				},
+			},
+		},
+		{
+			status: http.StatusNotModified,
+		},
+		{
+			status:        http.StatusNotFound,
+			expectedError: ErrNotificationsNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		var actualQuery string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			actualQuery = r.URL.Query().Get("notifications")
+			w.WriteHeader(test.status)
+			if test.body != nil {
+				_ = json.NewEncoder(w).Encode(test.body)
+			}
+		}))
+
+		conf := config.DefaultConfig(server.URL, "SampleApp")
+		conf.Notifications = config.Notifications{{NamespaceName: "application", NotificationID: -1}}
+
+		status, notifications, err := (&NotificationClient{}).GetNotifications(conf)
+		assert.Equal(t, test.status, status)
+		assert.Equal(t, test.expectedError, err)
+		assert.Equal(t, test.expected, notifications)
+		assert.Equal(t, `[{"namespaceName":"application","notificationId":-1}]`, actualQuery)
+
+		server.Close()
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	status, notifications, err := (&NotificationClient{}).GetNotifications(config.DefaultConfig(server.URL, "SampleApp"))
+	assert.Equal(t, http.StatusInternalServerError, status)
+	assert.NotNil(t, err)
+	assert.Nil(t, notifications)
+}
@@ -28,8 +28,15 @@ func (n Notifications) String() string {
 }
 
 type Notification struct {
-	NamespaceName  string `json:"namespaceName"`  // namespaceName: "application",
-	NotificationID int    `json:"notificationId"` // notificationId: 107
+	NamespaceName  string                `json:"namespaceName"`      // namespaceName: "application",
+	NotificationID int                   `json:"notificationId"`     // notificationId: 107
+	Messages       *NotificationMessages `json:"messages,omitempty"` // messages: {details: {"AppTest+default+application": 107}}
+}
+
+// NotificationMessages notifications/v2接口返回的messages，记录了每个watchedKey对应的notificationID
+// watchedKey格式为：appId+cluster+namespace
+type NotificationMessages struct {
+	Details map[string]int64 `json:"details"`
 }
 
 type Option func(*Config)
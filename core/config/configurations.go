@@ -7,10 +7,11 @@ type Configurations map[string]interface{}
 func (old Configurations) Different(new Configurations) Changes {
 	var changes []Change
 	for k, newValue := range new {
-		if oldValue, ok := old[k]; ok && oldValue != newValue {
-			changes = append(changes, NewChange(ChangeTypeUpdate, k, newValue))
-		} else {
+		oldValue, ok := old[k]
+		if !ok {
 			changes = append(changes, NewChange(ChangeTypeAdd, k, newValue))
+		} else if oldValue != newValue {
+			changes = append(changes, NewChange(ChangeTypeUpdate, k, newValue))
 		}
 	}
 
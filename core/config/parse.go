@@ -0,0 +1,128 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/magiconair/properties"
+	"gopkg.in/yaml.v2"
+)
+
+// Format namespace的配置格式
+type Format string
+
+const (
+	FormatProperties Format = "properties"
+	FormatJSON       Format = "json"
+	FormatYAML       Format = "yaml"
+	FormatYML        Format = "yml"
+)
+
+// Parse 将配置内容解析为Configurations，json及yaml中嵌套的对象以"."连接key，
+// 数组以"[i]"连接key，例如 {"db": {"hosts": ["a"]}} 解析为 db.hosts[0]=a
+func Parse(format Format, content []byte) (Configurations, error) {
+	if format == FormatProperties {
+		// 加载时同样不展开${}，否则循环引用的值会导致解析失败
+		p, err := (&properties.Loader{Encoding: properties.UTF8, DisableExpansion: true}).LoadBytes(content)
+		if err != nil {
+			return nil, err
+		}
+
+		conf := Configurations{}
+		for _, key := range p.Keys() {
+			conf[key], _ = p.Get(key)
+		}
+		return conf, nil
+	}
+
+	tree, err := ParseTree(format, content)
+	if err != nil {
+		return nil, err
+	}
+
+	conf := Configurations{}
+	flattenValue(conf, "", tree)
+	return conf, nil
+}
+
+// ParseTree 将json及yaml格式的配置内容解析为结构化的配置树，yaml中的key统一转换为string，
+// 顶层必须是对象，内容为空时返回空的配置树
+func ParseTree(format Format, content []byte) (map[string]interface{}, error) {
+	var v interface{}
+	switch format {
+	case FormatJSON:
+		dec := json.NewDecoder(bytes.NewReader(content))
+		dec.UseNumber()
+		if err := dec.Decode(&v); err != nil && err != io.EOF {
+			return nil, err
+		}
+	case FormatYAML, FormatYML:
+		if err := yaml.Unmarshal(content, &v); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("config: unsupported format %q", format)
+	}
+
+	if v == nil {
+		return map[string]interface{}{}, nil
+	}
+	tree, ok := normalize(v).(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("config: top level value must be an object, got %T", v)
+	}
+	return tree, nil
+}
+
+// normalize 将yaml解析出的map[interface{}]interface{}转换为map[string]interface{}
+func normalize(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, child := range val {
+			m[fmt.Sprint(k)] = normalize(child)
+		}
+		return m
+	case map[string]interface{}:
+		for k, child := range val {
+			val[k] = normalize(child)
+		}
+		return val
+	case []interface{}:
+		for i, child := range val {
+			val[i] = normalize(child)
+		}
+		return val
+	default:
+		return v
+	}
+}
+
+func flattenValue(conf Configurations, prefix string, v interface{}) {
+	join := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + "." + key
+	}
+
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, child := range val {
+			flattenValue(conf, join(k), child)
+		}
+	case []interface{}:
+		for i, child := range val {
+			flattenValue(conf, prefix+"["+strconv.Itoa(i)+"]", child)
+		}
+	case nil:
+		conf[prefix] = ""
+	case string:
+		conf[prefix] = val
+	default:
+		conf[prefix] = fmt.Sprint(val)
+	}
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		format   Format
+		content  string
+		expected Configurations
+		err      bool
+	}{
+		{
+			FormatProperties,
+			"# comment\ntimeout=100\ndb.host = 127.0.0.1\nurl=http://${host}\n",
+			Configurations{"timeout": "100", "db.host": "127.0.0.1", "url": "http://${host}"},
+			false,
+		},
+		{
+			FormatProperties,
+			"a=${b}\nb=${a}\n",
+			Configurations{"a": "${b}", "b": "${a}"},
+			false,
+		},
+		{
+			FormatJSON,
+			`{"timeout": 100, "ratio": 0.5, "debug": true, "db": {"hosts": ["a", "b"], "user": null}}`,
+			Configurations{"timeout": "100", "ratio": "0.5", "debug": "true", "db.hosts[0]": "a", "db.hosts[1]": "b", "db.user": ""},
+			false,
+		},
+		{
+			FormatYAML,
+			"timeout: 100\ndb:\n  hosts:\n    - a\n    - b\n  pool:\n    size: 10\n",
+			Configurations{"timeout": "100", "db.hosts[0]": "a", "db.hosts[1]": "b", "db.pool.size": "10"},
+			false,
+		},
+		{FormatYML, "", Configurations{}, false},
+		{FormatJSON, `["a"]`, nil, true},
+		{FormatJSON, `{`, nil, true},
+		{Format("xml"), `<a/>`, nil, true},
+	}
+
+	for _, test := range tests {
+		actual, err := Parse(test.format, []byte(test.content))
+		if test.err {
+			assert.NotNil(t, err, test.format)
+			continue
+		}
+		assert.Nil(t, err, test.format)
+		assert.Equal(t, test.expected, actual, test.format)
+	}
+}
+
+func TestParseTree(t *testing.T) {
+	tree, err := ParseTree(FormatYAML, []byte("db:\n  hosts:\n    - a\n  1: one\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"db": map[string]interface{}{
+			"hosts": []interface{}{"a"},
+			"1":     "one",
+		},
+	}, tree)
+
+	tree, err = ParseTree(FormatJSON, []byte(""))
+	assert.Nil(t, err)
+	assert.Empty(t, tree)
+
+	_, err = ParseTree(FormatProperties, []byte("a=1"))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,350 @@
+package config
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sixgoatsh/agollo/pkg/util/str"
+)
+
+const (
+	tagName          = "apollo"
+	defaultSeparator = ","
+)
+
+var (
+	ErrInvalidUnmarshalTarget = errors.New("unmarshal target must be a non-nil pointer to struct")
+
+	durationType        = reflect.TypeOf(time.Duration(0))
+	timeType            = reflect.TypeOf(time.Time{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// fieldTag 解析自 `apollo:"db.host,sep=;,default=localhost"`
+// default必须放在最后，以便默认值中可以包含逗号
+type fieldTag struct {
+	Key        string
+	Separator  string
+	Default    string
+	HasDefault bool
+}
+
+func parseTag(tag string) fieldTag {
+	ft := fieldTag{Separator: defaultSeparator}
+
+	parts := strings.SplitN(tag, ",", 2)
+	ft.Key = strings.TrimSpace(parts[0])
+	if len(parts) < 2 {
+		return ft
+	}
+
+	opts := parts[1]
+	for opts != "" {
+		if strings.HasPrefix(opts, "default=") {
+			ft.Default, ft.HasDefault = strings.TrimPrefix(opts, "default="), true
+			return ft
+		}
+
+		var opt string
+		if i := strings.Index(opts, ","); i >= 0 {
+			opt, opts = opts[:i], opts[i+1:]
+		} else {
+			opt, opts = opts, ""
+		}
+
+		if strings.HasPrefix(opt, "sep=") {
+			ft.Separator = strings.TrimPrefix(opt, "sep=")
+		}
+	}
+
+	return ft
+}
+
+// Unmarshal 将配置按照字段的apollo tag映射到out指向的结构体中
+// 嵌套结构体使用"."连接的key，例如：db.pool.size
+// 未设置tag的字段使用字段名作为key，tag为"-"的字段将被忽略
+func (c Configurations) Unmarshal(out interface{}) error {
+	v := reflect.ValueOf(out)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return ErrInvalidUnmarshalTarget
+	}
+
+	return c.decodeStruct(v.Elem(), "")
+}
+
+func (c Configurations) decodeStruct(v reflect.Value, prefix string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" && !field.Anonymous { // unexported
+			continue
+		}
+
+		tag, hasTag := field.Tag.Lookup(tagName)
+		if tag == "-" {
+			continue
+		}
+
+		// 未设置tag的匿名结构体，字段与外层结构体共享前缀
+		if field.Anonymous && !hasTag && field.Type.Kind() == reflect.Struct {
+			if err := c.decodeStruct(v.Field(i), prefix); err != nil {
+				return err
+			}
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+
+		ft := parseTag(tag)
+		key := joinKey(prefix, str.NonEmptyString(field.Name, ft.Key))
+		if err := c.decodeValue(v.Field(i), key, ft); err != nil {
+			return fmt.Errorf("apollo: decode field %s (key: %s): %v", field.Name, key, err)
+		}
+	}
+
+	return nil
+}
+
+func (c Configurations) decodeValue(v reflect.Value, key string, ft fieldTag) error {
+	raw, found := c[key]
+	if !found && ft.HasDefault {
+		raw, found = ft.Default, true
+	}
+
+	if isTextUnmarshaler(v) {
+		if !found {
+			return nil
+		}
+		s, err := str.ToStringE(raw)
+		if err != nil {
+			return err
+		}
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !found && !c.hasChildren(key) {
+			return nil
+		}
+		elem := reflect.New(v.Type().Elem())
+		if err := c.decodeValue(elem.Elem(), key, ft); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	case reflect.Struct:
+		if v.Type() != timeType {
+			return c.decodeStruct(v, key)
+		}
+	case reflect.Map:
+		return c.decodeMap(v, key, raw, found)
+	case reflect.Slice:
+		if !found {
+			return nil
+		}
+		return decodeSlice(v, raw, ft.Separator)
+	}
+
+	if !found {
+		return nil
+	}
+	return setScalar(v, raw)
+}
+
+func (c Configurations) decodeMap(v reflect.Value, key string, raw interface{}, found bool) error {
+	t := v.Type()
+	if t.Key().Kind() != reflect.String {
+		return fmt.Errorf("unsupported map key type %s", t.Key())
+	}
+
+	m := reflect.MakeMap(t)
+	prefix := key + "."
+	elemIsStruct := t.Elem().Kind() == reflect.Struct && t.Elem() != timeType
+
+	var children []string
+	for k := range c {
+		if strings.HasPrefix(k, prefix) {
+			children = append(children, strings.TrimPrefix(k, prefix))
+		}
+	}
+	sort.Strings(children)
+
+	switch {
+	case len(children) > 0:
+		for _, child := range children {
+			// 结构体类型的value以下一级key作为map的key，其余使用完整的后缀作为map的key
+			if elemIsStruct {
+				child = strings.SplitN(child, ".", 2)[0]
+			}
+			mk := reflect.ValueOf(child).Convert(t.Key())
+			if m.MapIndex(mk).IsValid() {
+				continue
+			}
+			elem := reflect.New(t.Elem()).Elem()
+			if err := c.decodeValue(elem, prefix+child, fieldTag{Separator: defaultSeparator}); err != nil {
+				return err
+			}
+			m.SetMapIndex(mk, elem)
+		}
+	case found:
+		// 不存在下一级key时，尝试把配置值当做JSON对象解析
+		sm, err := str.ToStringMapE(raw)
+		if err != nil {
+			return err
+		}
+		for k, val := range sm {
+			elem := reflect.New(t.Elem()).Elem()
+			if err := (Configurations{k: val}).decodeValue(elem, k, fieldTag{Separator: defaultSeparator}); err != nil {
+				return err
+			}
+			m.SetMapIndex(reflect.ValueOf(k).Convert(t.Key()), elem)
+		}
+	default:
+		return nil
+	}
+
+	v.Set(m)
+	return nil
+}
+
+func (c Configurations) hasChildren(key string) bool {
+	prefix := key + "."
+	for k := range c {
+		if strings.HasPrefix(k, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func decodeSlice(v reflect.Value, raw interface{}, sep string) error {
+	if v.Type().Elem().Kind() == reflect.Uint8 { // []byte
+		s, err := str.ToStringE(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBytes([]byte(s))
+		return nil
+	}
+
+	var items []interface{}
+	if rv := reflect.ValueOf(raw); rv.Kind() == reflect.Slice {
+		for i := 0; i < rv.Len(); i++ {
+			items = append(items, rv.Index(i).Interface())
+		}
+	} else {
+		ss, err := str.ToStringSliceE(raw, sep)
+		if err != nil {
+			return err
+		}
+		for _, s := range ss {
+			items = append(items, s)
+		}
+	}
+
+	slice := reflect.MakeSlice(v.Type(), len(items), len(items))
+	for i, item := range items {
+		elem := slice.Index(i)
+		if isTextUnmarshaler(elem) {
+			s, err := str.ToStringE(item)
+			if err != nil {
+				return err
+			}
+			if err := elem.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s)); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := setScalar(elem, item); err != nil {
+			return err
+		}
+	}
+	v.Set(slice)
+
+	return nil
+}
+
+func setScalar(v reflect.Value, raw interface{}) error {
+	switch v.Type() {
+	case durationType:
+		d, err := str.ToDurationE(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	case timeType:
+		t, err := str.ToTimeE(raw)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		s, err := str.ToStringE(raw)
+		if err != nil {
+			return err
+		}
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := str.ToBoolE(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := str.ToInt64E(raw)
+		if err != nil {
+			return err
+		}
+		if v.OverflowInt(i) {
+			return fmt.Errorf("value %d overflows %s", i, v.Type())
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, err := str.ToInt64E(raw)
+		if err != nil {
+			return err
+		}
+		if i < 0 || v.OverflowUint(uint64(i)) {
+			return fmt.Errorf("value %d overflows %s", i, v.Type())
+		}
+		v.SetUint(uint64(i))
+	case reflect.Float32, reflect.Float64:
+		f, err := str.ToFloat64E(raw)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Interface:
+		if raw != nil {
+			v.Set(reflect.ValueOf(raw))
+		}
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+
+	return nil
+}
+
+// isTextUnmarshaler time.Time交给ToTimeE处理，以支持更多的时间格式
+func isTextUnmarshaler(v reflect.Value) bool {
+	return v.Type() != timeType && v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType)
+}
+
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
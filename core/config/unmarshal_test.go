@@ -0,0 +1,87 @@
+package config
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type pool struct {
+	Size    int           `apollo:"size,default=10"`
+	Timeout time.Duration `apollo:"timeout"`
+}
+
+type db struct {
+	Host string `apollo:"host,default=localhost"`
+	Port int    `apollo:"port"`
+	Pool pool   `apollo:"pool"`
+}
+
+type Common struct {
+	Name string `apollo:"name"`
+}
+
+type sample struct {
+	Common
+	DB       db                `apollo:"db"`
+	Replica  *db               `apollo:"replica"`
+	Servers  []string          `apollo:"servers"`
+	Ports    []int             `apollo:"ports,sep=;"`
+	Labels   map[string]string `apollo:"labels"`
+	Extra    map[string]int    `apollo:"extra"`
+	IP       net.IP            `apollo:"ip"`
+	Tags     []string          `apollo:"tags,default=a,b"`
+	Enabled  bool
+	Ignored  string `apollo:"-"`
+	internal string
+}
+
+func TestConfigurationsUnmarshal(t *testing.T) {
+	conf := Configurations{
+		"name":            "foo",
+		"db.host":         "127.0.0.1",
+		"db.port":         "3306",
+		"db.pool.timeout": "3s",
+		"servers":         "a, b",
+		"ports":           "80;443",
+		"labels.env":      "dev",
+		"labels.zone.id":  "z1",
+		"extra":           `{"retry":3}`,
+		"ip":              "10.0.0.1",
+		"Enabled":         "true",
+		"Ignored":         "ignored",
+	}
+
+	expected := sample{
+		Common: Common{Name: "foo"},
+		DB: db{
+			Host: "127.0.0.1",
+			Port: 3306,
+			Pool: pool{Size: 10, Timeout: 3 * time.Second},
+		},
+		Servers: []string{"a", "b"},
+		Ports:   []int{80, 443},
+		Labels:  map[string]string{"env": "dev", "zone.id": "z1"},
+		Extra:   map[string]int{"retry": 3},
+		IP:      net.ParseIP("10.0.0.1"),
+		Tags:    []string{"a", "b"},
+		Enabled: true,
+	}
+
+	var actual sample
+	if err := conf.Unmarshal(&actual); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("should be equal (expected=%+v, actual=%+v)", expected, actual)
+	}
+
+	if err := (Configurations{"db.port": "abc"}).Unmarshal(&actual); err == nil {
+		t.Errorf("should return error when value can not be converted")
+	}
+
+	if err := conf.Unmarshal(actual); err != ErrInvalidUnmarshalTarget {
+		t.Errorf("should be equal (expected=%v, actual=%v)", ErrInvalidUnmarshalTarget, err)
+	}
+}
@@ -0,0 +1,334 @@
+// Package metrics 提供options.Metrics的内置实现，以Prometheus文本格式输出运行指标，不依赖Prometheus客户端
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sixgoatsh/agollo/core/options"
+)
+
+const (
+	counterType   = "counter"
+	gaugeType     = "gauge"
+	histogramType = "histogram"
+)
+
+// DefaultBuckets 请求耗时直方图的默认分桶，单位：秒，长轮训会在服务端保持约60秒
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60, 90}
+
+var _ options.Metrics = (*Collector)(nil)
+var _ http.Handler = (*Collector)(nil)
+
+// Collector 收集agollo的运行指标，通过WriteTo或者作为http.Handler输出Prometheus文本格式
+type Collector struct {
+	mu       sync.Mutex
+	buckets  []float64
+	families map[string]*family
+}
+
+type family struct {
+	name   string
+	help   string
+	typ    string
+	labels []string
+	series map[string]*series
+}
+
+type series struct {
+	values  []string
+	value   float64
+	buckets []uint64
+	sum     float64
+	count   uint64
+}
+
+// Option 设置Collector
+type Option func(*Collector)
+
+// Buckets 设置请求耗时直方图的分桶，单位：秒
+func Buckets(buckets ...float64) Option {
+	return func(c *Collector) {
+		c.buckets = append([]float64(nil), buckets...)
+		sort.Float64s(c.buckets)
+	}
+}
+
+// New 创建Collector，通过options.WithMetrics设置给agollo
+func New(opts ...Option) *Collector {
+	c := &Collector{
+		buckets:  DefaultBuckets,
+		families: make(map[string]*family),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	c.register("agollo_long_poll_total", counterType, "Total number of long poll requests by outcome.",
+		"app_id", "cluster", "outcome")
+	c.register("agollo_long_poll_duration_seconds", histogramType, "Duration of long poll requests.",
+		"app_id", "cluster")
+	c.register("agollo_fetch_total", counterType, "Total number of namespace fetches by status.",
+		"app_id", "cluster", "namespace", "strategy", "status")
+	c.register("agollo_fetch_duration_seconds", histogramType, "Duration of namespace fetches.",
+		"app_id", "cluster", "namespace", "strategy")
+	c.register("agollo_backup_total", counterType, "Total number of backup saves and fallbacks by result.",
+		"app_id", "cluster", "namespace", "op", "result")
+	c.register("agollo_balancer_select_total", counterType, "Total number of config servers selected by the balancer.",
+		"server")
+	c.register("agollo_watch_dropped_total", counterType, "Total number of watch events dropped because nobody consumed them.",
+		"app_id", "cluster", "namespace")
+	c.register("agollo_refresh_total", counterType, "Total number of periodic refreshes by result.",
+		"app_id", "cluster", "namespace", "result")
+	c.register("agollo_last_sync_timestamp_seconds", gaugeType, "Unix time of the last successful sync with apollo.",
+		"app_id", "cluster", "namespace")
+	return c
+}
+
+func (c *Collector) register(name, typ, help string, labels ...string) {
+	c.families[name] = &family{
+		name:   name,
+		help:   help,
+		typ:    typ,
+		labels: labels,
+		series: make(map[string]*series),
+	}
+}
+
+// LongPoll 实现options.Metrics
+func (c *Collector) LongPoll(appID, cluster string, status int, err error, duration time.Duration) {
+	c.add("agollo_long_poll_total", 1, appID, cluster, longPollOutcome(status, err))
+	c.observe("agollo_long_poll_duration_seconds", duration, appID, cluster)
+}
+
+// Fetch 实现options.Metrics
+func (c *Collector) Fetch(appID, cluster, namespace string, strategy options.FetchStrategy, status int, err error, duration time.Duration) {
+	c.add("agollo_fetch_total", 1, appID, cluster, namespace, strategy.String(), statusLabel(status, err))
+	c.observe("agollo_fetch_duration_seconds", duration, appID, cluster, namespace, strategy.String())
+}
+
+// Backup 实现options.Metrics
+func (c *Collector) Backup(appID, cluster, namespace string, op options.BackupOp, err error) {
+	c.add("agollo_backup_total", 1, appID, cluster, namespace, string(op), result(err))
+}
+
+// BalancerSelect 实现options.Metrics
+func (c *Collector) BalancerSelect(server string) {
+	c.add("agollo_balancer_select_total", 1, server)
+}
+
+// WatchDropped 实现options.Metrics
+func (c *Collector) WatchDropped(appID, cluster, namespace string) {
+	c.add("agollo_watch_dropped_total", 1, appID, cluster, namespace)
+}
+
+// Refresh 实现options.Metrics
+func (c *Collector) Refresh(appID, cluster, namespace string, drifted bool, err error) {
+	outcome := result(err)
+	if err == nil && drifted {
+		outcome = "drifted"
+	}
+	c.add("agollo_refresh_total", 1, appID, cluster, namespace, outcome)
+}
+
+// Synced 实现options.Metrics
+func (c *Collector) Synced(appID, cluster, namespace string, t time.Time) {
+	c.set("agollo_last_sync_timestamp_seconds", float64(t.UnixNano())/1e9, appID, cluster, namespace)
+}
+
+func (c *Collector) add(name string, delta float64, values ...string) {
+	c.mu.Lock()
+	c.series(name, values).value += delta
+	c.mu.Unlock()
+}
+
+func (c *Collector) set(name string, value float64, values ...string) {
+	c.mu.Lock()
+	c.series(name, values).value = value
+	c.mu.Unlock()
+}
+
+func (c *Collector) observe(name string, duration time.Duration, values ...string) {
+	v := duration.Seconds()
+
+	c.mu.Lock()
+	s := c.series(name, values)
+	if s.buckets == nil {
+		s.buckets = make([]uint64, len(c.buckets))
+	}
+	for i, upper := range c.buckets {
+		if v <= upper {
+			s.buckets[i]++
+		}
+	}
+	s.sum += v
+	s.count++
+	c.mu.Unlock()
+}
+
+// series 调用方需要持有c.mu
+func (c *Collector) series(name string, values []string) *series {
+	f := c.families[name]
+	key := strings.Join(values, "\xff")
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{values: values}
+		f.series[key] = s
+	}
+	return s
+}
+
+// Value 返回counter或者gauge的当前值，values按照注册时label的顺序，不存在时返回0
+func (c *Collector) Value(name string, values ...string) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	f, ok := c.families[name]
+	if !ok {
+		return 0
+	}
+	if s, ok := f.series[strings.Join(values, "\xff")]; ok {
+		return s.value
+	}
+	return 0
+}
+
+// WriteTo 以Prometheus文本格式输出所有指标
+func (c *Collector) WriteTo(w io.Writer) (int64, error) {
+	cw := &countWriter{w: bufio.NewWriter(w)}
+
+	c.mu.Lock()
+	names := make([]string, 0, len(c.families))
+	for name := range c.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		c.writeFamily(cw, c.families[name])
+	}
+	c.mu.Unlock()
+
+	if cw.err == nil {
+		cw.err = cw.w.Flush()
+	}
+	return cw.n, cw.err
+}
+
+func (c *Collector) writeFamily(w *countWriter, f *family) {
+	if len(f.series) == 0 {
+		return
+	}
+	w.printf("# HELP %s %s\n", f.name, f.help)
+	w.printf("# TYPE %s %s\n", f.name, f.typ)
+
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		s := f.series[key]
+		labels := formatLabels(f.labels, s.values)
+		if f.typ != histogramType {
+			w.printf("%s%s %s\n", f.name, labels, formatFloat(s.value))
+			continue
+		}
+		for i, upper := range c.buckets {
+			w.printf("%s_bucket%s %d\n", f.name, formatLabels(append(f.labels, "le"), append(s.values, formatFloat(upper))), s.buckets[i])
+		}
+		w.printf("%s_bucket%s %d\n", f.name, formatLabels(append(f.labels, "le"), append(s.values, "+Inf")), s.count)
+		w.printf("%s_sum%s %s\n", f.name, labels, formatFloat(s.sum))
+		w.printf("%s_count%s %d\n", f.name, labels, s.count)
+	}
+}
+
+// ServeHTTP 输出Prometheus文本格式，可以直接挂载到/metrics
+func (c *Collector) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = c.WriteTo(w)
+}
+
+type countWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (w *countWriter) printf(format string, args ...interface{}) {
+	if w.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(w.w, format, args...)
+	w.n += int64(n)
+	w.err = err
+}
+
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(name)
+		b.WriteString(`="`)
+		b.WriteString(escapeLabel(values[i]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+var labelReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(v string) string {
+	return labelReplacer.Replace(v)
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func longPollOutcome(status int, err error) string {
+	switch {
+	case err != nil:
+		return "error"
+	case status == http.StatusOK:
+		return "changed"
+	case status == http.StatusNotModified:
+		return "not_modified"
+	default:
+		return "error"
+	}
+}
+
+func statusLabel(status int, err error) string {
+	if status == 0 {
+		if err != nil {
+			return "error"
+		}
+		return "unknown"
+	}
+	return strconv.Itoa(status)
+}
+
+func result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "success"
+}
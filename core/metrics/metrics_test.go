@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sixgoatsh/agollo/core/options"
+)
+
+func TestCollector(t *testing.T) {
+	c := New(Buckets(1, 0.1))
+
+	c.LongPoll("app", "default", http.StatusNotModified, nil, 50*time.Millisecond)
+	c.LongPoll("app", "default", http.StatusOK, nil, 500*time.Millisecond)
+	c.LongPoll("app", "default", 0, errors.New("timeout"), 2*time.Second)
+	c.Fetch("app", "default", "application", options.FetchCache, http.StatusOK, nil, time.Millisecond)
+	c.Fetch("app", "default", "application", options.FetchNonCache, 0, errors.New("refused"), time.Millisecond)
+	c.Backup("app", "default", "application", options.BackupFallback, errors.New("missing"))
+	c.BalancerSelect(`http://a"b`)
+	c.WatchDropped("app", "default", "application")
+	c.WatchDropped("app", "default", "application")
+	c.Refresh("app", "default", "application", true, nil)
+	c.Synced("app", "default", "application", time.Unix(1600000000, 0))
+
+	assert.Equal(t, float64(1), c.Value("agollo_long_poll_total", "app", "default", "not_modified"))
+	assert.Equal(t, float64(1), c.Value("agollo_long_poll_total", "app", "default", "error"))
+	assert.Equal(t, float64(1), c.Value("agollo_fetch_total", "app", "default", "application", "cache", "200"))
+	assert.Equal(t, float64(1), c.Value("agollo_fetch_total", "app", "default", "application", "non-cache", "error"))
+	assert.Equal(t, float64(1), c.Value("agollo_backup_total", "app", "default", "application", "fallback", "error"))
+	assert.Equal(t, float64(2), c.Value("agollo_watch_dropped_total", "app", "default", "application"))
+	assert.Equal(t, float64(1), c.Value("agollo_refresh_total", "app", "default", "application", "drifted"))
+	assert.Equal(t, float64(1600000000), c.Value("agollo_last_sync_timestamp_seconds", "app", "default", "application"))
+	assert.Equal(t, float64(0), c.Value("agollo_unknown"))
+
+	recorder := httptest.NewRecorder()
+	c.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", recorder.Header().Get("Content-Type"))
+
+	body := recorder.Body.String()
+	for _, line := range []string{
+		"# TYPE agollo_long_poll_total counter",
+		"# TYPE agollo_last_sync_timestamp_seconds gauge",
+		`agollo_balancer_select_total{server="http://a\"b"} 1`,
+		`agollo_long_poll_duration_seconds_bucket{app_id="app",cluster="default",le="0.1"} 1`,
+		`agollo_long_poll_duration_seconds_bucket{app_id="app",cluster="default",le="1"} 2`,
+		`agollo_long_poll_duration_seconds_bucket{app_id="app",cluster="default",le="+Inf"} 3`,
+		`agollo_long_poll_duration_seconds_sum{app_id="app",cluster="default"} 2.55`,
+		`agollo_long_poll_duration_seconds_count{app_id="app",cluster="default"} 3`,
+		`agollo_last_sync_timestamp_seconds{app_id="app",cluster="default",namespace="application"} 1.6e+09`,
+	} {
+		assert.Contains(t, body, line+"\n")
+	}
+	// 没有数据的指标不输出
+	assert.False(t, strings.Contains(body, "agollo_fetch_duration_seconds_bucket{app_id=\"other\""))
+}
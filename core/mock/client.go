@@ -1,6 +1,8 @@
 package mock
 
 import (
+	"context"
+
 	"github.com/sixgoatsh/agollo/core/client"
 	"github.com/sixgoatsh/agollo/core/config"
 )
@@ -16,6 +18,13 @@ func (c *CacheClient) GetConfigsFromCache(clientConf config.Config) (conf *confi
 	return c.ConfigsFromCache(clientConf)
 }
 
+func (c *CacheClient) GetConfigsFromCacheCtx(ctx context.Context, clientConf config.Config) (conf *config.Configurations, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.GetConfigsFromCache(clientConf)
+}
+
 type NonCacheClient struct {
 	ConfigsFromNonCache func(conf config.Config, opts ...client.NotificationsOption) (int, *client.NonCacheResp, error)
 }
@@ -27,15 +36,29 @@ func (c *NonCacheClient) GetConfigsFromNonCache(conf config.Config, opts ...clie
 	return c.ConfigsFromNonCache(conf, opts...)
 }
 
+func (c *NonCacheClient) GetConfigsFromNonCacheCtx(ctx context.Context, conf config.Config, opts ...client.NotificationsOption) (int, *client.NonCacheResp, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, nil, err
+	}
+	return c.GetConfigsFromNonCache(conf, opts...)
+}
+
 type NotificationsClient struct {
-	Notifications func(conf config.Config, result []config.Notification) (status int, err error)
+	Notifications func(conf config.Config) (status int, notifications []config.Notification, err error)
 }
 
-func (c *NotificationsClient) GetNotifications(conf config.Config, result []config.Notification) (status int, err error) {
+func (c *NotificationsClient) GetNotifications(conf config.Config) (status int, notifications []config.Notification, err error) {
 	if c.Notifications == nil {
-		return 404, nil
+		return 404, nil, nil
+	}
+	return c.Notifications(conf)
+}
+
+func (c *NotificationsClient) GetNotificationsCtx(ctx context.Context, conf config.Config) (status int, notifications []config.Notification, err error) {
+	if err := ctx.Err(); err != nil {
+		return 0, nil, err
 	}
-	return c.Notifications(conf, result)
+	return c.GetNotifications(conf)
 }
 
 type MetaServerClient struct {
@@ -48,3 +71,10 @@ func (c *MetaServerClient) GetConfigServers(conf config.Config) (int, []client.C
 	}
 	return c.ConfigServers(conf)
 }
+
+func (c *MetaServerClient) GetConfigServersCtx(ctx context.Context, conf config.Config) (int, []client.ConfigServerResp, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, nil, err
+	}
+	return c.GetConfigServers(conf)
+}
@@ -1,6 +1,17 @@
 package options
 
-import "time"
+import (
+	"time"
+
+	"github.com/sixgoatsh/agollo/pkg/backoff"
+)
+
+const (
+	// EnvLocalDir 设置后从该目录读取配置，不连接apollo
+	EnvLocalDir = "APOLLO_LOCAL_DIR"
+
+	defaultEnvOverridePrefix = "APOLLO_OVERRIDE_"
+)
 
 var (
 	defaultCluster                    = "default"
@@ -10,4 +21,15 @@ var (
 	defaultFailTolerantOnBackupExists = false
 	defaultEnableSLB                  = false
 	defaultLongPollInterval           = 1 * time.Second
+	defaultSeparator                  = ","
+	defaultListenerQueueSize          = 16
+	defaultListenerOverflowPolicy     = OverflowDropOldest
+	defaultRefreshInterval            = time.Duration(0)
+	defaultRetryPolicy                = backoff.Policy{
+		InitialDelay: 1 * time.Second,
+		MaxDelay:     60 * time.Second,
+		Multiplier:   2,
+		Jitter:       0.2,
+		MaxAttempts:  3,
+	}
 )
@@ -0,0 +1,47 @@
+package options
+
+import "time"
+
+// BackupOp 备份相关的操作
+type BackupOp string
+
+const (
+	BackupSave     BackupOp = "save"     // 从apollo获取到新的配置后保存备份
+	BackupFallback BackupOp = "fallback" // apollo无法连接时读取备份容灾
+)
+
+// Metrics 收集agollo的运行指标，实现需要保证并发安全且不能阻塞，
+// core/metrics提供了输出Prometheus文本格式的实现，也可以对接其他的监控系统
+type Metrics interface {
+	// LongPoll 一次长轮训请求，status为HTTP Status，请求失败时为0
+	LongPoll(appID, cluster string, status int, err error, duration time.Duration)
+	// Fetch 一次获取namespace配置的请求，status为HTTP Status，请求失败时为0
+	Fetch(appID, cluster, namespace string, strategy FetchStrategy, status int, err error, duration time.Duration)
+	// Backup 保存备份或者读取备份容灾
+	Backup(appID, cluster, namespace string, op BackupOp, err error)
+	// BalancerSelect 负载均衡选择的ConfigServer
+	BalancerSelect(server string)
+	// WatchDropped Watch事件因为没有被及时消费而被丢弃
+	WatchDropped(appID, cluster, namespace string)
+	// Refresh 定期刷新重新加载了namespace，drifted为true时发现了长轮训遗漏的更新
+	Refresh(appID, cluster, namespace string, drifted bool, err error)
+	// Synced namespace与apollo同步成功的时间，包括配置未变化的304
+	Synced(appID, cluster, namespace string, t time.Time)
+}
+
+// NopMetrics 不收集任何指标
+type NopMetrics struct{}
+
+func (NopMetrics) LongPoll(string, string, int, error, time.Duration) {}
+
+func (NopMetrics) Fetch(string, string, string, FetchStrategy, int, error, time.Duration) {}
+
+func (NopMetrics) Backup(string, string, string, BackupOp, error) {}
+
+func (NopMetrics) BalancerSelect(string) {}
+
+func (NopMetrics) WatchDropped(string, string, string) {}
+
+func (NopMetrics) Refresh(string, string, string, bool, error) {}
+
+func (NopMetrics) Synced(string, string, string, time.Time) {}
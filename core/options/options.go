@@ -1,24 +1,52 @@
 package options
 
 import (
+	"flag"
+	"os"
 	"time"
 
+	"github.com/sixgoatsh/agollo/core/backup"
 	"github.com/sixgoatsh/agollo/core/config"
+	"github.com/sixgoatsh/agollo/pkg/backoff"
 	"github.com/sixgoatsh/agollo/pkg/log"
+	"github.com/sixgoatsh/agollo/pkg/rest"
 	"github.com/sixgoatsh/agollo/pkg/util/str"
 )
 
 type Options struct {
 	Conf                       config.Config
-	PreloadNamespaces          []string        // 预加载命名空间，默认：为空
-	Logger                     log.Logger      // 日志实现类，可以设置自定义实现或者通过NewLogger()创建并设置有效的io.Writer，默认: ioutil.Discard
-	AutoFetchOnCacheMiss       bool            // 自动获取非预设以外的Namespace的配置，默认：false
-	LongPollerInterval         time.Duration   // 轮训间隔时间，默认：1s
-	BackupFile                 string          // 备份文件存放地址，默认：.goApollo
-	FailTolerantOnBackupExists bool            // 服务器连接失败时允许读取备份，默认：false
-	EnableSLB                  bool            // 启用ConfigServer负载均衡
-	RefreshIntervalInSecond    time.Duration   // ConfigServer刷新间隔
-	ClientOptions              []config.Option // 设置apollo HTTP api的配置项
+	PreloadNamespaces          []string                     // 预加载命名空间，默认：为空
+	Logger                     log.Logger                   // 分级日志实现类，可以通过NewLogger()创建或者使用slog、zap、logrus的适配器，默认: ioutil.Discard
+	AutoFetchOnCacheMiss       bool                         // 自动获取非预设以外的Namespace的配置，默认：false
+	LongPollerInterval         time.Duration                // 轮训间隔时间，默认：1s
+	BackupFile                 string                       // 备份文件存放地址，默认：.goApollo
+	BackupStore                backup.Store                 // 备份存储，设置后BackupFile不再生效，默认：保存到BackupFile的单文件备份
+	BackupKeyProvider          backup.KeyProvider           // 设置后使用AES-GCM加密备份，默认：不加密
+	BackupEncryptOptions       []backup.EncryptOption       // 加密备份的选项，例如backup.AllowPlaintextMigration()
+	FailTolerantOnBackupExists bool                         // 服务器连接失败时允许读取备份，默认：false
+	MaxBackupAge               time.Duration                // 超过该时间的备份不再读取，小于等于0时不限制，默认：0
+	EnableSLB                  bool                         // 启用ConfigServer负载均衡
+	RefreshIntervalInSecond    time.Duration                // ConfigServer刷新间隔
+	ClientOptions              []config.Option              // 设置apollo HTTP api的配置项
+	ListenerQueueSize          int                          // 变更监听器的队列长度，默认：16
+	ListenerOverflowPolicy     OverflowPolicy               // 变更监听器队列满时的处理策略，默认：OverflowDropOldest
+	RestClient                 *rest.Client                 // 发送apollo HTTP请求的客户端，仅在未传入IApolloClient时生效，默认：rest.DefaultClient
+	RetryPolicy                backoff.Policy               // 长轮训及namespace重新加载失败时的重试策略，默认：1s起，每次翻倍，最大60s，±20%抖动，每轮最多3次
+	LocalDir                   string                       // 从本地目录读取配置，不连接apollo，仅在未传入IApolloClient时生效，默认：环境变量APOLLO_LOCAL_DIR
+	FetchStrategy              FetchStrategy                // 获取namespace配置使用的接口，默认：FetchNonCache
+	NamespaceFetchStrategies   map[string]FetchStrategy     // 按namespace设置获取配置使用的接口，优先于FetchStrategy
+	CacheRefreshInterval       time.Duration                // 定期通过缓存接口刷新所有namespace，防止遗漏通知，小于等于0时不刷新，默认：0
+	RefreshInterval            time.Duration                // 定期使用release key重新加载所有namespace，小于等于0时不刷新，默认：0
+	ParseContent               bool                         // json、yaml格式的namespace解析为key/value，默认：false，与旧版本一致仅保存在content中
+	NamespaceChain             []string                     // 未指定namespace时Get按顺序在这些namespace中查找key，默认：为空
+	Interpolate                bool                         // Get及GetNameSpace解析配置值中的${key}、${namespace:key}、${ENV:NAME}占位符，默认：false
+	Overrides                  map[string]map[string]string // 覆盖apollo的配置，key: namespace，为空时覆盖所有namespace中已存在的key，默认：为空
+	EnvOverridePrefix          string                       // 读取该前缀的环境变量覆盖apollo的配置，为空时不读取，默认：为空
+	EnvOverrideKeyMapper       func(name string) string     // 环境变量名转换为配置的key，默认：去掉前缀后转为小写，"_"替换为"."
+	OverrideFlags              *flag.FlagSet                // 使用显式设置的命令行参数覆盖apollo的配置，参数名即配置的key，默认：nil
+	Subscriptions              []Subscription               // 同时订阅的其他appID及cluster，默认：为空
+	Metrics                    Metrics                      // 收集运行指标，默认：NopMetrics，不收集
+	RequiredNamespaces         []string                     // 从apollo或者未过期的备份加载成功后才就绪的namespace，默认：为空
 }
 
 func NewOptions(configServerURL, appID string, opts ...Option) (Options, error) {
@@ -31,6 +59,12 @@ func NewOptions(configServerURL, appID string, opts ...Option) (Options, error)
 		BackupFile:                 defaultBackupFile,
 		FailTolerantOnBackupExists: defaultFailTolerantOnBackupExists,
 		EnableSLB:                  defaultEnableSLB,
+		ListenerQueueSize:          defaultListenerQueueSize,
+		ListenerOverflowPolicy:     defaultListenerOverflowPolicy,
+		RetryPolicy:                defaultRetryPolicy,
+		RefreshInterval:            defaultRefreshInterval,
+		Metrics:                    NopMetrics{},
+		LocalDir:                   os.Getenv(EnvLocalDir),
 	}
 	for _, opt := range opts {
 		opt(&options)
@@ -38,6 +72,29 @@ func NewOptions(configServerURL, appID string, opts ...Option) (Options, error)
 
 	options.Conf.Apply(options.ClientOptions...)
 
+	if options.Metrics == nil {
+		options.Metrics = NopMetrics{}
+	}
+
+	if options.BackupStore == nil {
+		if options.LocalDir != "" {
+			// 本地模式不需要容灾
+			options.BackupStore = backup.NewNopStore()
+		} else {
+			options.BackupStore = backup.NewFileStore(options.BackupFile)
+		}
+	}
+	if options.BackupKeyProvider != nil {
+		options.BackupStore = backup.NewEncryptedStore(options.BackupStore, options.BackupKeyProvider, options.BackupEncryptOptions...)
+	}
+
+	preload := append(append([]string{}, options.NamespaceChain...), options.RequiredNamespaces...)
+	for _, namespace := range preload {
+		if !str.StringInSlice(namespace, options.PreloadNamespaces) {
+			options.PreloadNamespaces = append(options.PreloadNamespaces, namespace)
+		}
+	}
+
 	if options.Conf.NamespaceName != "" && !str.StringInSlice(options.Conf.NamespaceName, options.PreloadNamespaces) {
 		options.PreloadNamespaces = append(options.PreloadNamespaces, options.Conf.NamespaceName)
 	}
@@ -70,6 +127,7 @@ func PreloadNamespaces(namespaces ...string) Option {
 	}
 }
 
+// WithLogger 设置日志，旧的Log(kvs ...interface{})实现可以通过log.FromKV转换
 func WithLogger(l log.Logger) Option {
 	return func(o *Options) {
 		o.Logger = l
@@ -94,6 +152,30 @@ func BackupFile(backupFile string) Option {
 	}
 }
 
+// WithBackupStore 设置自定义的备份存储，例如backup.NewDirStore按namespace分文件保存
+func WithBackupStore(store backup.Store) Option {
+	return func(o *Options) {
+		o.BackupStore = store
+	}
+}
+
+// EncryptBackup 使用AES-GCM加密备份，key可以来自backup.EnvKey、backup.FileKey或自定义的KeyProvider
+// key错误时读取备份会返回backup.ErrDecrypt，而不是返回空配置，
+// 迁移开启加密前的明文备份时传入backup.AllowPlaintextMigration()
+func EncryptBackup(keys backup.KeyProvider, opts ...backup.EncryptOption) Option {
+	return func(o *Options) {
+		o.BackupKeyProvider = keys
+		o.BackupEncryptOptions = opts
+	}
+}
+
+// MaxBackupAge 容灾时拒绝读取从apollo获取时间超过maxAge的备份，没有记录获取时间的旧备份同样会被拒绝
+func MaxBackupAge(maxAge time.Duration) Option {
+	return func(o *Options) {
+		o.MaxBackupAge = maxAge
+	}
+}
+
 func FailTolerantOnBackupExists() Option {
 	return func(o *Options) {
 		o.FailTolerantOnBackupExists = true
@@ -124,6 +206,188 @@ func WithClientOptions(opts ...config.Option) Option {
 	}
 }
 
+// ListenerQueueSize 设置变更监听器的队列长度，监听器消费较慢时变更事件在队列中等待
+func ListenerQueueSize(size int) Option {
+	return func(o *Options) {
+		o.ListenerQueueSize = size
+	}
+}
+
+func ListenerOverflowPolicy(policy OverflowPolicy) Option {
+	return func(o *Options) {
+		o.ListenerOverflowPolicy = policy
+	}
+}
+
+// WithRestClient 设置自定义的HTTP客户端，可以设置各接口的超时时间、代理、证书以及中间件
+func WithRestClient(c *rest.Client) Option {
+	return func(o *Options) {
+		o.RestClient = c
+	}
+}
+
+// WithRetryPolicy 长轮训失败后按照策略逐渐增加下一次轮训的间隔，成功后恢复为LongPollerInterval
+// namespace重新加载失败时，在同一轮内最多尝试MaxAttempts次
+func WithRetryPolicy(policy backoff.Policy) Option {
+	return func(o *Options) {
+		o.RetryPolicy = policy
+	}
+}
+
+// WithFetchStrategy 设置获取namespace配置默认使用的接口
+func WithFetchStrategy(strategy FetchStrategy) Option {
+	return func(o *Options) {
+		o.FetchStrategy = strategy
+	}
+}
+
+// NamespaceFetchStrategy 设置namespace获取配置使用的接口，例如访问频率较高或者不需要实时更新的namespace使用FetchCache
+func NamespaceFetchStrategy(namespace string, strategy FetchStrategy) Option {
+	return func(o *Options) {
+		if o.NamespaceFetchStrategies == nil {
+			o.NamespaceFetchStrategies = map[string]FetchStrategy{}
+		}
+		o.NamespaceFetchStrategies[namespace] = strategy
+	}
+}
+
+// CacheRefreshInterval 定期通过/configfiles/json接口刷新所有namespace，配置有变化时发送Watch事件
+func CacheRefreshInterval(interval time.Duration) Option {
+	return func(o *Options) {
+		o.CacheRefreshInterval = interval
+	}
+}
+
+// RefreshInterval 定期使用release key重新加载所有namespace，配置未变化时apollo返回304，变化时发送Watch事件，
+// 小于等于0时关闭，默认关闭，需要与Java客户端一致时设置为5m
+func RefreshInterval(interval time.Duration) Option {
+	return func(o *Options) {
+		o.RefreshInterval = interval
+	}
+}
+
+// NamespaceChain 叠加多个namespace，例如：应用namespace、团队namespace、公共namespace，
+// 未指定WithNamespace时Get按顺序查找key，靠前的namespace优先，这些namespace会被预加载
+func NamespaceChain(namespaces ...string) Option {
+	return func(o *Options) {
+		o.NamespaceChain = append(o.NamespaceChain, namespaces...)
+	}
+}
+
+// RequiredNamespaces 必须从apollo或者未超过MaxBackupAge的备份加载成功的namespace，这些namespace会被预加载，
+// 未全部加载成功时创建返回列出所有失败namespace的错误，Ready返回错误，WaitReady阻塞并持续重试
+func RequiredNamespaces(namespaces ...string) Option {
+	return func(o *Options) {
+		o.RequiredNamespaces = append(o.RequiredNamespaces, namespaces...)
+	}
+}
+
+// Interpolate 解析配置值中的占位符，引用的key变更时，引用了该key的配置同样会触发Watch事件：
+//   - ${key}：同一namespace中的key
+//   - ${namespace:key}：其他namespace中的key
+//   - ${ENV:NAME}：环境变量
+//   - ${key:-fallback}：key不存在时使用默认值
+func Interpolate() Option {
+	return func(o *Options) {
+		o.Interpolate = true
+	}
+}
+
+// Override 覆盖namespace中的配置，namespace为空时覆盖所有namespace中已存在的key，
+// 优先级：命令行参数 > 环境变量 > Override > apollo
+func Override(namespace string, conf map[string]string) Option {
+	return func(o *Options) {
+		if o.Overrides == nil {
+			o.Overrides = map[string]map[string]string{}
+		}
+		if o.Overrides[namespace] == nil {
+			o.Overrides[namespace] = map[string]string{}
+		}
+		for k, v := range conf {
+			o.Overrides[namespace][k] = v
+		}
+	}
+}
+
+// EnvOverrides 使用prefix开头的环境变量覆盖所有namespace中已存在的key，环境变量在创建GoApollo时读取，
+// prefix为空时使用APOLLO_OVERRIDE_，例如：APOLLO_OVERRIDE_DB_HOST覆盖db.host
+func EnvOverrides(prefix string) Option {
+	return func(o *Options) {
+		o.EnvOverridePrefix = str.NonEmptyString(defaultEnvOverridePrefix, prefix)
+	}
+}
+
+// EnvOverrideKeyMapper 设置环境变量名到配置key的转换，name为完整的环境变量名
+func EnvOverrideKeyMapper(mapper func(name string) string) Option {
+	return func(o *Options) {
+		o.EnvOverrideKeyMapper = mapper
+	}
+}
+
+// FlagOverrides 使用fs中显式设置的参数覆盖所有namespace中已存在的key，参数名即配置的key，例如：-db.host=127.0.0.1
+func FlagOverrides(fs *flag.FlagSet) Option {
+	return func(o *Options) {
+		o.OverrideFlags = fs
+	}
+}
+
+// Subscription 订阅的appID、cluster及预加载的namespace
+type Subscription struct {
+	AppID      string
+	Cluster    string // 为空时使用Options.Conf.ClusterName
+	Namespaces []string
+	AccessKey  string // appID的访问密钥，为空时不签名
+}
+
+// Subscribe 在同一个GoApollo中订阅其他appID及cluster的namespace，例如平台公共的appID，
+// 每个appID及cluster使用独立的长轮训，共用ConfigServer负载均衡及备份存储
+func Subscribe(appID, cluster string, namespaces ...string) Option {
+	return func(o *Options) {
+		o.Subscriptions = append(o.Subscriptions, Subscription{
+			AppID:      appID,
+			Cluster:    cluster,
+			Namespaces: namespaces,
+		})
+	}
+}
+
+// WithSubscription 与Subscribe一致，可以设置appID的访问密钥
+func WithSubscription(sub Subscription) Option {
+	return func(o *Options) {
+		o.Subscriptions = append(o.Subscriptions, sub)
+	}
+}
+
+// WithMetrics 设置运行指标的收集，例如：metrics.New()
+func WithMetrics(m Metrics) Option {
+	return func(o *Options) {
+		o.Metrics = m
+	}
+}
+
+// ParseContent 将json、yaml格式的namespace解析为key/value，例如Get("db.host", WithNamespace("datasource.yaml"))，
+// 开启后GetNameSpace不再包含content，原始内容通过GetContent读取，xml、txt等格式不受影响
+func ParseContent() Option {
+	return func(o *Options) {
+		o.ParseContent = true
+	}
+}
+
+// NamespaceFetchStrategy 返回namespace获取配置使用的接口
+func (o Options) NamespaceFetchStrategy(namespace string) FetchStrategy {
+	if strategy, ok := o.NamespaceFetchStrategies[namespace]; ok {
+		return strategy
+	}
+	return o.FetchStrategy
+}
+
+// LocalDir 从本地目录的.properties、.json、.yaml文件读取配置，文件变更时触发Watch事件，用于本地开发及CI
+func LocalDir(dir string) Option {
+	return func(o *Options) {
+		o.LocalDir = dir
+	}
+}
+
 type GetOptions struct {
 	// Get时，如果key不存在将返回此值
 	DefaultValue string
@@ -131,6 +395,17 @@ type GetOptions struct {
 	// Get时，显示的指定需要获取那个Namespace中的key。非空情况下，优先级顺序为：
 	// GetOptions.Namespace > Options.DefaultNamespace > "application"
 	Namespace string
+
+	// GetStringSlice时，切割字符串使用的分隔符，默认：","
+	Separator string
+
+	// Get时，按顺序在这些namespace中查找key，第一个存在该key的namespace生效，非空时忽略Namespace。
+	// 未指定Namespace及Namespaces时使用Options.NamespaceChain
+	Namespaces []string
+
+	// Get时，从指定的appID及cluster中读取，为空时使用Options.Conf中的AppID及ClusterName
+	AppID   string
+	Cluster string
 }
 
 func (o Options) NewGetOptions(opts ...GetOption) GetOptions {
@@ -139,10 +414,26 @@ func (o Options) NewGetOptions(opts ...GetOption) GetOptions {
 		opt(&getOpts)
 	}
 
+	if getOpts.Namespace == "" && len(getOpts.Namespaces) == 0 {
+		getOpts.Namespaces = o.NamespaceChain
+	}
+
 	if getOpts.Namespace == "" {
 		getOpts.Namespace = str.NonEmptyString(defaultNamespace, o.Conf.NamespaceName)
 	}
 
+	if getOpts.Separator == "" {
+		getOpts.Separator = defaultSeparator
+	}
+
+	if getOpts.AppID == "" {
+		getOpts.AppID = o.Conf.AppID
+	}
+
+	if getOpts.Cluster == "" {
+		getOpts.Cluster = o.Conf.ClusterName
+	}
+
 	return getOpts
 }
 
@@ -159,3 +450,118 @@ func WithNamespace(namespace string) GetOption {
 		o.Namespace = namespace
 	}
 }
+
+// WithNamespaceChain 按顺序在多个namespace中查找key，靠前的namespace优先
+func WithNamespaceChain(namespaces ...string) GetOption {
+	return func(o *GetOptions) {
+		o.Namespaces = namespaces
+	}
+}
+
+// WithAppID 从Subscribe订阅的appID中读取，未订阅的appID会在第一次读取时订阅，
+// namespace需要通过Subscribe预加载或者开启AutoFetchOnCacheMiss
+func WithAppID(appID string) GetOption {
+	return func(o *GetOptions) {
+		o.AppID = appID
+	}
+}
+
+// WithCluster 从Subscribe订阅的cluster中读取
+func WithCluster(cluster string) GetOption {
+	return func(o *GetOptions) {
+		o.Cluster = cluster
+	}
+}
+
+func WithSeparator(sep string) GetOption {
+	return func(o *GetOptions) {
+		o.Separator = sep
+	}
+}
+
+// FetchStrategy 获取namespace配置使用的接口
+type FetchStrategy int
+
+const (
+	// FetchNonCache 通过/configs接口直接从数据库获取，配合release key在配置未变化时返回304
+	FetchNonCache FetchStrategy = iota
+	// FetchCache 通过/configfiles/json接口从config service的缓存获取，适合频率较高的请求，
+	// 缓存会有最多1秒的延迟
+	FetchCache
+)
+
+func (s FetchStrategy) String() string {
+	switch s {
+	case FetchNonCache:
+		return "non-cache"
+	case FetchCache:
+		return "cache"
+	default:
+		return "unknown"
+	}
+}
+
+// OverflowPolicy 变更监听器队列满时的处理策略
+type OverflowPolicy int
+
+const (
+	// OverflowBlock 阻塞直到监听器消费队列中的事件，不会丢失事件，
+	// 事件在持有重新加载的锁时推送，消费较慢的监听器会阻塞长轮训及其他namespace的更新
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropOldest 队列满时丢弃队列中最早的事件，队列未满时不会丢失事件，默认的处理策略
+	OverflowDropOldest
+	// OverflowCoalesce 队列满时将同一个namespace未被消费的事件合并为一个，OldValue为最早事件的旧值，NewValue为最新事件的新值，
+	// 队列中没有可以合并的事件时丢弃最早的事件
+	OverflowCoalesce
+)
+
+func (p OverflowPolicy) String() string {
+	switch p {
+	case OverflowBlock:
+		return "block"
+	case OverflowDropOldest:
+		return "drop-oldest"
+	case OverflowCoalesce:
+		return "coalesce"
+	default:
+		return "unknown"
+	}
+}
+
+type ListenerOptions struct {
+	// 监听器的队列长度，默认使用Options.ListenerQueueSize
+	QueueSize int
+
+	// 队列满时的处理策略，默认使用Options.ListenerOverflowPolicy
+	OverflowPolicy OverflowPolicy
+}
+
+func (o Options) NewListenerOptions(opts ...ListenerOption) ListenerOptions {
+	listenerOpts := ListenerOptions{
+		QueueSize:      o.ListenerQueueSize,
+		OverflowPolicy: o.ListenerOverflowPolicy,
+	}
+	for _, opt := range opts {
+		opt(&listenerOpts)
+	}
+
+	if listenerOpts.QueueSize <= 0 {
+		listenerOpts.QueueSize = defaultListenerQueueSize
+	}
+
+	return listenerOpts
+}
+
+type ListenerOption func(*ListenerOptions)
+
+func WithQueueSize(size int) ListenerOption {
+	return func(o *ListenerOptions) {
+		o.QueueSize = size
+	}
+}
+
+func WithOverflowPolicy(policy OverflowPolicy) ListenerOption {
+	return func(o *ListenerOptions) {
+		o.OverflowPolicy = policy
+	}
+}
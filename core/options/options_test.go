@@ -7,6 +7,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/sixgoatsh/agollo/core/config"
+	"github.com/sixgoatsh/agollo/pkg/rest"
 )
 
 func TestOptions(t *testing.T) {
@@ -14,12 +15,7 @@ func TestOptions(t *testing.T) {
 		configServerURL = "localhost:8080"
 		appID           = "SampleApp"
 	)
-	clientConf := config.Config{
-		ConfigServerUrl: configServerURL,
-		AppID:           appID,
-		ClusterName:     defaultCluster,
-		NamespaceName:   defaultNamespace,
-	}
+	clientConf := config.DefaultConfig(configServerURL, appID)
 	var tests = []struct {
 		Options []Option
 		Check   func(Options)
@@ -28,15 +24,19 @@ func TestOptions(t *testing.T) {
 			[]Option{},
 			func(opts Options) {
 				assert.Equal(t, clientConf, opts.Conf)
+				assert.Equal(t, defaultCluster, opts.Conf.ClusterName)
 				assert.Equal(t, defaultAutoFetchOnCacheMiss, opts.AutoFetchOnCacheMiss)
 				assert.Equal(t, defaultLongPollInterval, opts.LongPollerInterval)
+				assert.Equal(t, time.Duration(0), opts.RefreshInterval)
 				assert.Equal(t, defaultBackupFile, opts.BackupFile)
 				assert.Equal(t, defaultFailTolerantOnBackupExists, opts.FailTolerantOnBackupExists)
+				assert.NotNil(t, opts.BackupStore)
 				assert.Equal(t, defaultEnableSLB, opts.EnableSLB)
 				assert.NotNil(t, opts.Logger)
-				assert.Equal(t, clientConf.NamespaceName, opts.PreloadNamespaces[0])
+				assert.Empty(t, opts.PreloadNamespaces)
 				getOpts := opts.NewGetOptions()
 				assert.Equal(t, "application", getOpts.Namespace)
+				assert.Equal(t, defaultSeparator, getOpts.Separator)
 				getOpts = opts.NewGetOptions(WithNamespace("customize_namespace"))
 				assert.Equal(t, "customize_namespace", getOpts.Namespace)
 				assert.Empty(t, opts.Conf.AccessKey)
@@ -59,8 +59,9 @@ func TestOptions(t *testing.T) {
 				assert.Equal(t, "default_namespace", opts.Conf.NamespaceName)
 				getOpts := opts.NewGetOptions()
 				assert.Equal(t, "default_namespace", getOpts.Namespace)
-				getOpts = opts.NewGetOptions(WithNamespace("customize_namespace"))
+				getOpts = opts.NewGetOptions(WithNamespace("customize_namespace"), WithSeparator(";"))
 				assert.Equal(t, "customize_namespace", getOpts.Namespace)
+				assert.Equal(t, ";", getOpts.Separator)
 				assert.Equal(t, true, opts.AutoFetchOnCacheMiss)
 				assert.Equal(t, time.Second*30, opts.LongPollerInterval)
 				assert.Equal(t, "test_backup", opts.BackupFile)
@@ -71,9 +72,31 @@ func TestOptions(t *testing.T) {
 		{
 			[]Option{
 				EnableSLB(true),
+				WithRestClient(rest.DefaultClient),
 			},
 			func(opts Options) {
 				assert.Equal(t, true, opts.EnableSLB)
+				assert.Equal(t, rest.DefaultClient, opts.RestClient)
+			},
+		},
+		{
+			[]Option{
+				WithFetchStrategy(FetchCache),
+				NamespaceFetchStrategy("application", FetchNonCache),
+				CacheRefreshInterval(time.Minute),
+				RefreshInterval(0),
+				NamespaceChain("app", "public"),
+				RequiredNamespaces("public", "db"),
+			},
+			func(opts Options) {
+				assert.Equal(t, FetchNonCache, opts.NamespaceFetchStrategy("application"))
+				assert.Equal(t, FetchCache, opts.NamespaceFetchStrategy("other"))
+				assert.Equal(t, time.Minute, opts.CacheRefreshInterval)
+				assert.Equal(t, time.Duration(0), opts.RefreshInterval)
+				assert.Equal(t, []string{"app", "public", "db"}, opts.PreloadNamespaces)
+				assert.Equal(t, []string{"app", "public"}, opts.NamespaceChain)
+				assert.Equal(t, []string{"app", "public"}, opts.NewGetOptions().Namespaces)
+				assert.Empty(t, opts.NewGetOptions(WithNamespace("app")).Namespaces)
 			},
 		},
 	}
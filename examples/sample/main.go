@@ -82,7 +82,7 @@ func main() {
 		}
 	}()
 
-	select {}
+	defer agollo.Stop()
 
-	agollo.Stop()
+	select {}
 }
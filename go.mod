@@ -10,4 +10,5 @@ require (
 	github.com/stretchr/testify v1.6.1
 	github.com/xordataexchange/crypt v0.0.3-0.20170626215501-b2862e3d0a77
 	gopkg.in/go-playground/assert.v1 v1.2.1
+	gopkg.in/yaml.v2 v2.4.0
 )
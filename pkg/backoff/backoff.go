@@ -0,0 +1,128 @@
+package backoff
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// 未设置时的默认值
+const (
+	DefaultInitialDelay = 1 * time.Second
+	DefaultMaxDelay     = 60 * time.Second
+	DefaultMultiplier   = 2
+)
+
+// Policy 指数退避策略，第n次重试的等待时间为 InitialDelay * Multiplier^(n-1)，
+// 在此基础上增加±Jitter比例的随机抖动，最终限制在[InitialDelay, MaxDelay]之间
+type Policy struct {
+	InitialDelay time.Duration // 首次重试的等待时间，默认：1s
+	MaxDelay     time.Duration // 最大等待时间，小于InitialDelay时等于InitialDelay，默认：60s
+	Multiplier   float64       // 每次失败后等待时间的增长倍数，小于1时为1，默认：2
+	Jitter       float64       // 随机抖动比例，取值范围[0, 1]
+	MaxAttempts  int           // 每轮最多尝试次数，包括第一次请求，小于等于0时不限制
+}
+
+// State 退避的当前状态，用于诊断
+type State struct {
+	Attempt     int           // 连续失败的次数，成功后清零
+	Delay       time.Duration // 最近一次计算出的等待时间
+	LastError   error         // 最近一次失败的错误
+	LastFailure time.Time     // 最近一次失败的时间
+}
+
+type Backoff struct {
+	policy Policy
+
+	mu    sync.Mutex
+	state State
+	rand  *rand.Rand
+}
+
+// New 未设置的InitialDelay、MaxDelay及Multiplier使用默认值，避免等待时间为0导致的忙等或者无限增长
+func New(policy Policy) *Backoff {
+	if policy.InitialDelay <= 0 {
+		policy.InitialDelay = DefaultInitialDelay
+	}
+	if policy.MaxDelay <= 0 {
+		policy.MaxDelay = DefaultMaxDelay
+	}
+	if policy.MaxDelay < policy.InitialDelay {
+		policy.MaxDelay = policy.InitialDelay
+	}
+	if policy.Multiplier == 0 {
+		policy.Multiplier = DefaultMultiplier
+	} else if policy.Multiplier < 1 {
+		policy.Multiplier = 1
+	}
+	if policy.Jitter < 0 {
+		policy.Jitter = 0
+	} else if policy.Jitter > 1 {
+		policy.Jitter = 1
+	}
+
+	return &Backoff{
+		policy: policy,
+		rand:   rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Fail 记录一次失败并返回下一次重试前需要等待的时间
+func (b *Backoff) Fail(err error) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state.Attempt++
+	b.state.LastError = err
+	b.state.LastFailure = time.Now()
+
+	delay := float64(b.policy.InitialDelay) * math.Pow(b.policy.Multiplier, float64(b.state.Attempt-1))
+	if b.policy.Jitter > 0 {
+		delay += delay * b.policy.Jitter * (b.rand.Float64()*2 - 1)
+	}
+	// 多次失败后delay可能溢出为+Inf，转换为time.Duration前先限制范围
+	delay = math.Max(float64(b.policy.InitialDelay), math.Min(delay, float64(b.policy.MaxDelay)))
+
+	b.state.Delay = time.Duration(delay)
+	return b.state.Delay
+}
+
+// Reset 成功后清空失败记录
+func (b *Backoff) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state = State{}
+}
+
+// Exhausted 当前轮次的尝试次数是否已经用完
+func (b *Backoff) Exhausted() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.policy.MaxAttempts > 0 && b.state.Attempt >= b.policy.MaxAttempts
+}
+
+// Policy 返回填充默认值后的策略
+func (b *Backoff) Policy() Policy {
+	return b.policy
+}
+
+func (b *Backoff) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// Sleep 等待d，ctx被取消时提前返回ctx.Err()
+func Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,73 @@
+package backoff
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	err := errors.New("server unavailable")
+	b := New(Policy{
+		InitialDelay: 100 * time.Millisecond,
+		MaxDelay:     time.Second,
+		Multiplier:   2,
+		MaxAttempts:  3,
+	})
+
+	expected := []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+		time.Second,
+	}
+	for i, delay := range expected {
+		if actual := b.Fail(err); actual != delay {
+			t.Errorf("should be equal (attempt=%v, expected=%v, actual=%v)", i+1, delay, actual)
+		}
+		if exhausted := i+1 >= 3; b.Exhausted() != exhausted {
+			t.Errorf("should be equal (attempt=%v, expected=%v, actual=%v)", i+1, exhausted, b.Exhausted())
+		}
+	}
+
+	state := b.State()
+	if state.Attempt != len(expected) || state.LastError != err || state.Delay != time.Second {
+		t.Errorf("unexpected state: %+v", state)
+	}
+
+	b.Reset()
+	if state := b.State(); state.Attempt != 0 || state.LastError != nil {
+		t.Errorf("state should be reset: %+v", state)
+	}
+
+	b = New(Policy{InitialDelay: 100 * time.Millisecond, Multiplier: 2, Jitter: 0.5})
+	for i := 0; i < 100; i++ {
+		if actual := b.Fail(err); actual < 100*time.Millisecond || actual > 150*time.Millisecond {
+			t.Fatalf("delay should be within jitter range: %v", actual)
+		}
+		b.Reset()
+	}
+}
+
+func TestBackoffDefaults(t *testing.T) {
+	// 未设置的字段使用默认值，不会出现0、负数或者溢出的等待时间
+	b := New(Policy{})
+	if actual := b.Fail(nil); actual != DefaultInitialDelay {
+		t.Errorf("should be equal (expected=%v, actual=%v)", DefaultInitialDelay, actual)
+	}
+	for i := 0; i < 2000; i++ {
+		b.Fail(nil)
+	}
+	if actual := b.Fail(nil); actual != DefaultMaxDelay {
+		t.Errorf("should be equal (expected=%v, actual=%v)", DefaultMaxDelay, actual)
+	}
+
+	b = New(Policy{InitialDelay: time.Second, MaxDelay: time.Millisecond, Multiplier: 0.5})
+	for i := 0; i < 3; i++ {
+		if actual := b.Fail(nil); actual != time.Second {
+			t.Errorf("should be equal (attempt=%v, expected=%v, actual=%v)", i+1, time.Second, actual)
+		}
+	}
+}
@@ -1,15 +1,101 @@
+// Package log agollo使用的分级、key-value格式的日志接口
+//
+// 日志字段统一使用固定的snake_case名称，便于日志系统检索：app_id、cluster、namespace、
+// config_server、meta_server、status、strategy、release_key、notification_id、attempt、delay及error
 package log
 
 import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
+// Level 日志级别
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	default:
+		return "level(" + strconv.Itoa(int(l)) + ")"
+	}
+}
+
+// Logger 分级日志接口，kvs为交替的key及value
 type Logger interface {
+	Debug(msg string, kvs ...interface{})
+	Info(msg string, kvs ...interface{})
+	Warn(msg string, kvs ...interface{})
+	Error(msg string, kvs ...interface{})
+}
+
+// KVLogger 旧版本不分级的日志接口，例如go-kit的logger
+type KVLogger interface {
 	Log(kvs ...interface{})
 }
 
+// FromKV 将KVLogger转换为Logger，日志级别及内容以"level"、"msg"字段添加在kvs之前
+func FromKV(l KVLogger) Logger {
+	return &kvLogger{l: l}
+}
+
+type kvLogger struct {
+	l KVLogger
+}
+
+func (k *kvLogger) log(level Level, msg string, kvs []interface{}) {
+	k.l.Log(append([]interface{}{"level", level.String(), "msg", msg}, kvs...)...)
+}
+
+func (k *kvLogger) Debug(msg string, kvs ...interface{}) { k.log(LevelDebug, msg, kvs) }
+func (k *kvLogger) Info(msg string, kvs ...interface{})  { k.log(LevelInfo, msg, kvs) }
+func (k *kvLogger) Warn(msg string, kvs ...interface{})  { k.log(LevelWarn, msg, kvs) }
+func (k *kvLogger) Error(msg string, kvs ...interface{}) { k.log(LevelError, msg, kvs) }
+
+// With 返回在每条日志的字段前添加kvs的Logger
+func With(l Logger, kvs ...interface{}) Logger {
+	if len(kvs) == 0 {
+		return l
+	}
+	if w, ok := l.(*withLogger); ok {
+		return &withLogger{l: w.l, kvs: concat(w.kvs, kvs)}
+	}
+	return &withLogger{l: l, kvs: kvs}
+}
+
+type withLogger struct {
+	l   Logger
+	kvs []interface{}
+}
+
+func (w *withLogger) Debug(msg string, kvs ...interface{}) { w.l.Debug(msg, concat(w.kvs, kvs)...) }
+func (w *withLogger) Info(msg string, kvs ...interface{})  { w.l.Info(msg, concat(w.kvs, kvs)...) }
+func (w *withLogger) Warn(msg string, kvs ...interface{})  { w.l.Warn(msg, concat(w.kvs, kvs)...) }
+func (w *withLogger) Error(msg string, kvs ...interface{}) { w.l.Error(msg, concat(w.kvs, kvs)...) }
+
+func concat(a, b []interface{}) []interface{} {
+	kvs := make([]interface{}, 0, len(a)+len(b))
+	return append(append(kvs, a...), b...)
+}
+
 type LoggerOption func(*logger)
 
 func LoggerWriter(w io.Writer) LoggerOption {
@@ -18,8 +104,16 @@ func LoggerWriter(w io.Writer) LoggerOption {
 	}
 }
 
+// LoggerLevel 丢弃低于level的日志，默认：LevelInfo
+func LoggerLevel(level Level) LoggerOption {
+	return func(l *logger) {
+		l.level = level
+	}
+}
+
+// NewLogger 以logfmt格式输出日志，默认输出到ioutil.Discard
 func NewLogger(opts ...LoggerOption) Logger {
-	l := &logger{}
+	l := &logger{level: LevelInfo}
 	for _, opt := range opts {
 		opt(l)
 	}
@@ -32,9 +126,48 @@ func NewLogger(opts ...LoggerOption) Logger {
 }
 
 type logger struct {
-	w io.Writer
+	mu    sync.Mutex
+	w     io.Writer
+	level Level
 }
 
-func (l *logger) Log(kvs ...interface{}) {
-	fmt.Fprintln(l.w, kvs...)
+func (l *logger) Debug(msg string, kvs ...interface{}) { l.log(LevelDebug, msg, kvs) }
+func (l *logger) Info(msg string, kvs ...interface{})  { l.log(LevelInfo, msg, kvs) }
+func (l *logger) Warn(msg string, kvs ...interface{})  { l.log(LevelWarn, msg, kvs) }
+func (l *logger) Error(msg string, kvs ...interface{}) { l.log(LevelError, msg, kvs) }
+
+func (l *logger) log(level Level, msg string, kvs []interface{}) {
+	if level < l.level {
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString("time=")
+	b.WriteString(time.Now().Format(time.RFC3339))
+	b.WriteString(" level=")
+	b.WriteString(level.String())
+	b.WriteString(" msg=")
+	b.WriteString(formatValue(msg))
+	for i := 0; i < len(kvs); i += 2 {
+		b.WriteByte(' ')
+		b.WriteString(formatValue(fmt.Sprint(kvs[i])))
+		b.WriteByte('=')
+		if i+1 < len(kvs) {
+			b.WriteString(formatValue(fmt.Sprint(kvs[i+1])))
+		} else {
+			b.WriteString(`"(MISSING)"`)
+		}
+	}
+	b.WriteByte('\n')
+
+	l.mu.Lock()
+	_, _ = io.WriteString(l.w, b.String())
+	l.mu.Unlock()
+}
+
+func formatValue(s string) string {
+	if s == "" || strings.ContainsAny(s, " =\"\t\r\n") {
+		return strconv.Quote(s)
+	}
+	return s
 }
@@ -0,0 +1,45 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := With(NewLogger(LoggerWriter(&buf)), "app_id", "SampleApp")
+	l = With(l, "cluster", "default")
+
+	l.Debug("namespace changed", "namespace", "application")
+	l.Warn("long poll failed", "status", 0, "error", fmt.Errorf("dial tcp: connection refused"), "odd")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 1)
+	assert.True(t, strings.HasPrefix(lines[0], "time="))
+	assert.True(t, strings.HasSuffix(lines[0],
+		` level=warn msg="long poll failed" app_id=SampleApp cluster=default status=0 error="dial tcp: connection refused" odd="(MISSING)"`))
+
+	buf.Reset()
+	NewLogger(LoggerWriter(&buf), LoggerLevel(LevelDebug)).Debug("namespace changed")
+	assert.Contains(t, buf.String(), "level=debug")
+}
+
+type kvs [][]interface{}
+
+func (k *kvs) Log(kv ...interface{}) {
+	*k = append(*k, kv)
+}
+
+func TestFromKV(t *testing.T) {
+	var logged kvs
+	l := With(FromKV(&logged), "app_id", "SampleApp")
+	l.Error("parse namespace content failed", "namespace", "application")
+
+	assert.Equal(t, kvs{{"level", "error", "msg", "parse namespace content failed",
+		"app_id", "SampleApp", "namespace", "application"}}, logged)
+	assert.Equal(t, "level(9)", Level(9).String())
+}
@@ -0,0 +1,11 @@
+module github.com/sixgoatsh/agollo/pkg/log/logrusadapter
+
+go 1.14
+
+require (
+	github.com/sirupsen/logrus v1.9.3
+	github.com/sixgoatsh/agollo v0.0.0-00010101000000-000000000000
+	github.com/stretchr/testify v1.7.0
+)
+
+replace github.com/sixgoatsh/agollo => ../../..
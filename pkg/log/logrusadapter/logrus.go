@@ -0,0 +1,39 @@
+// Package logrusadapter 将logrus的Logger或Entry转换为agollo的log.Logger，使用独立的go.mod，避免agollo依赖logrus，logrus仅需要go1.13，go版本与agollo一致为go1.14
+package logrusadapter
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/sixgoatsh/agollo/pkg/log"
+)
+
+var _ log.Logger = (*logger)(nil)
+
+// New 输出日志到l，kvs转换为logrus.Fields
+func New(l logrus.FieldLogger) log.Logger {
+	return &logger{l: l}
+}
+
+type logger struct {
+	l logrus.FieldLogger
+}
+
+func (a *logger) Debug(msg string, kvs ...interface{}) { a.l.WithFields(fields(kvs)).Debug(msg) }
+func (a *logger) Info(msg string, kvs ...interface{})  { a.l.WithFields(fields(kvs)).Info(msg) }
+func (a *logger) Warn(msg string, kvs ...interface{})  { a.l.WithFields(fields(kvs)).Warn(msg) }
+func (a *logger) Error(msg string, kvs ...interface{}) { a.l.WithFields(fields(kvs)).Error(msg) }
+
+func fields(kvs []interface{}) logrus.Fields {
+	f := make(logrus.Fields, len(kvs)/2)
+	for i := 0; i < len(kvs); i += 2 {
+		key := fmt.Sprint(kvs[i])
+		if i+1 < len(kvs) {
+			f[key] = kvs[i+1]
+		} else {
+			f[key] = "(MISSING)"
+		}
+	}
+	return f
+}
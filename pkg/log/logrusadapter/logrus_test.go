@@ -0,0 +1,21 @@
+package logrusadapter
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogger(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	logger.SetLevel(logrus.DebugLevel)
+	l := New(logger)
+	l.Info("namespace loaded", "namespace", "application", "release_key")
+
+	entry := hook.LastEntry()
+	assert.Equal(t, logrus.InfoLevel, entry.Level)
+	assert.Equal(t, "namespace loaded", entry.Message)
+	assert.Equal(t, logrus.Fields{"namespace": "application", "release_key": "(MISSING)"}, entry.Data)
+}
@@ -0,0 +1,27 @@
+// Package slogadapter 将log/slog的Logger转换为agollo的log.Logger，使用独立的go.mod，go版本与log/slog一致，需要go1.21
+package slogadapter
+
+import (
+	"log/slog"
+
+	"github.com/sixgoatsh/agollo/pkg/log"
+)
+
+var _ log.Logger = (*logger)(nil)
+
+// New 输出日志到l，l为nil时使用slog.Default()
+func New(l *slog.Logger) log.Logger {
+	if l == nil {
+		l = slog.Default()
+	}
+	return &logger{l: l}
+}
+
+type logger struct {
+	l *slog.Logger
+}
+
+func (a *logger) Debug(msg string, kvs ...interface{}) { a.l.Debug(msg, kvs...) }
+func (a *logger) Info(msg string, kvs ...interface{})  { a.l.Info(msg, kvs...) }
+func (a *logger) Warn(msg string, kvs ...interface{})  { a.l.Warn(msg, kvs...) }
+func (a *logger) Error(msg string, kvs ...interface{}) { a.l.Error(msg, kvs...) }
@@ -0,0 +1,23 @@
+package slogadapter
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	l.Warn("long poll failed", "app_id", "SampleApp", "status", 500)
+
+	var entry map[string]interface{}
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "WARN", entry["level"])
+	assert.Equal(t, "long poll failed", entry["msg"])
+	assert.Equal(t, "SampleApp", entry["app_id"])
+	assert.Equal(t, float64(500), entry["status"])
+}
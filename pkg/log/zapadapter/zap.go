@@ -0,0 +1,24 @@
+// Package zapadapter 将zap的Logger转换为agollo的log.Logger，使用独立的go.mod，避免agollo依赖zap，go版本与zap v1.27一致，需要go1.19
+package zapadapter
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/sixgoatsh/agollo/pkg/log"
+)
+
+var _ log.Logger = (*logger)(nil)
+
+// New 通过l的SugaredLogger输出日志
+func New(l *zap.Logger) log.Logger {
+	return &logger{l: l.Sugar()}
+}
+
+type logger struct {
+	l *zap.SugaredLogger
+}
+
+func (a *logger) Debug(msg string, kvs ...interface{}) { a.l.Debugw(msg, kvs...) }
+func (a *logger) Info(msg string, kvs ...interface{})  { a.l.Infow(msg, kvs...) }
+func (a *logger) Warn(msg string, kvs ...interface{})  { a.l.Warnw(msg, kvs...) }
+func (a *logger) Error(msg string, kvs ...interface{}) { a.l.Errorw(msg, kvs...) }
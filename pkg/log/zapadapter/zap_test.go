@@ -0,0 +1,24 @@
+package zapadapter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestLogger(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	l := New(zap.New(core))
+	l.Debug("namespace changed", "namespace", "application")
+	l.Error("parse namespace content failed", "app_id", "SampleApp")
+
+	entries := logs.AllUntimed()
+	assert.Len(t, entries, 2)
+	assert.Equal(t, zapcore.DebugLevel, entries[0].Level)
+	assert.Equal(t, map[string]interface{}{"namespace": "application"}, entries[0].ContextMap())
+	assert.Equal(t, zapcore.ErrorLevel, entries[1].Level)
+	assert.Equal(t, "parse namespace content failed", entries[1].Message)
+}
@@ -1,26 +1,171 @@
 package rest
 
 import (
+	"context"
+	"crypto/tls"
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"time"
 )
 
-var defaultClientTimeout = 90 * time.Second
+var (
+	defaultClientTimeout     = 90 * time.Second
+	defaultLongPollTimeout   = 90 * time.Second
+	defaultConfigTimeout     = 10 * time.Second
+	defaultMetaServerTimeout = 10 * time.Second
+
+	// DefaultClient 未注入Client时使用
+	DefaultClient = NewClient()
+)
 
 type Doer interface {
 	Do(*http.Request) (*http.Response, error)
 }
 
-var client = &http.Client{
-	// Notifications need be hold request 60 second，so make sure client is greater than 60 second。
-	Timeout: defaultClientTimeout,
+// DoerFunc 将普通函数转换为Doer
+type DoerFunc func(*http.Request) (*http.Response, error)
+
+func (f DoerFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
 }
 
-func Do(method, url string, headers map[string]string, v interface{}) (status int, err error) {
+// Middleware 包装Doer，可以在请求前修改请求(例如添加tracing header)，或在请求后处理响应
+type Middleware func(next Doer) Doer
+
+// Endpoint 区分apollo的各个接口，用于设置不同的超时时间
+type Endpoint int
+
+const (
+	EndpointDefault Endpoint = iota
+	// notifications/v2接口，apollo会hold请求60秒，超时时间需要大于60秒
+	EndpointLongPoll
+	// configs及configfiles接口
+	EndpointConfig
+	// services/config接口
+	EndpointMetaServer
+)
+
+type Client struct {
+	doer        Doer
+	transport   http.RoundTripper
+	tlsConfig   *tls.Config
+	proxy       func(*http.Request) (*url.URL, error)
+	timeouts    map[Endpoint]time.Duration
+	middlewares []Middleware
+}
+
+type ClientOption func(*Client)
+
+// WithDoer 使用自定义的Doer发送请求，设置后WithTransport、WithTLSConfig、WithProxy不再生效
+func WithDoer(doer Doer) ClientOption {
+	return func(c *Client) {
+		c.doer = doer
+	}
+}
+
+func WithTransport(rt http.RoundTripper) ClientOption {
+	return func(c *Client) {
+		c.transport = rt
+	}
+}
+
+// WithTLSConfig 设置证书及CA，仅在Transport为*http.Transport时生效
+func WithTLSConfig(tlsConfig *tls.Config) ClientOption {
+	return func(c *Client) {
+		c.tlsConfig = tlsConfig
+	}
+}
+
+// WithProxy 设置代理，仅在Transport为*http.Transport时生效
+func WithProxy(proxy func(*http.Request) (*url.URL, error)) ClientOption {
+	return func(c *Client) {
+		c.proxy = proxy
+	}
+}
+
+// WithTimeout 设置某个接口的超时时间，小于等于0时不设置超时
+func WithTimeout(endpoint Endpoint, timeout time.Duration) ClientOption {
+	return func(c *Client) {
+		c.timeouts[endpoint] = timeout
+	}
+}
+
+// WithMiddleware 先添加的Middleware在外层，最先处理请求
+func WithMiddleware(middlewares ...Middleware) ClientOption {
+	return func(c *Client) {
+		c.middlewares = append(c.middlewares, middlewares...)
+	}
+}
+
+func NewClient(opts ...ClientOption) *Client {
+	c := &Client{
+		timeouts: map[Endpoint]time.Duration{
+			EndpointDefault:    defaultClientTimeout,
+			EndpointLongPoll:   defaultLongPollTimeout,
+			EndpointConfig:     defaultConfigTimeout,
+			EndpointMetaServer: defaultMetaServerTimeout,
+		},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.doer == nil {
+		c.doer = &http.Client{Transport: c.buildTransport()}
+	}
+
+	for i := len(c.middlewares) - 1; i >= 0; i-- {
+		c.doer = c.middlewares[i](c.doer)
+	}
+
+	return c
+}
+
+func (c *Client) buildTransport() http.RoundTripper {
+	rt := c.transport
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+
+	if c.tlsConfig == nil && c.proxy == nil {
+		return rt
+	}
+
+	t, ok := rt.(*http.Transport)
+	if !ok {
+		return rt
+	}
+
+	t = t.Clone()
+	if c.tlsConfig != nil {
+		t.TLSClientConfig = c.tlsConfig
+	}
+	if c.proxy != nil {
+		t.Proxy = c.proxy
+	}
+	return t
+}
+
+// Timeout 返回接口的超时时间
+func (c *Client) Timeout(endpoint Endpoint) time.Duration {
+	if timeout, ok := c.timeouts[endpoint]; ok {
+		return timeout
+	}
+	return c.timeouts[EndpointDefault]
+}
+
+// Do 仅在HTTP Status为200时将响应解析到v中
+func (c *Client) Do(ctx context.Context, endpoint Endpoint, method, url string, headers map[string]string, v interface{}) (status int, err error) {
+	if timeout := c.Timeout(endpoint); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
 	var req *http.Request
-	req, err = http.NewRequest(method, url, nil)
+	req, err = http.NewRequestWithContext(ctx, method, url, nil)
 	if err != nil {
 		return
 	}
@@ -30,7 +175,7 @@ func Do(method, url string, headers map[string]string, v interface{}) (status in
 	}
 
 	var body []byte
-	status, body, err = parseResponseBody(client, req)
+	status, body, err = parseResponseBody(c.doer, req)
 	if err != nil {
 		return
 	}
@@ -41,6 +186,15 @@ func Do(method, url string, headers map[string]string, v interface{}) (status in
 	return
 }
 
+func Do(method, url string, headers map[string]string, v interface{}) (status int, err error) {
+	return DoCtx(context.Background(), method, url, headers, v)
+}
+
+// DoCtx ctx被取消或者超时时，会立即中断正在进行的请求，包括被服务端hold住的长轮训请求
+func DoCtx(ctx context.Context, method, url string, headers map[string]string, v interface{}) (status int, err error) {
+	return DefaultClient.Do(ctx, EndpointDefault, method, url, headers, v)
+}
+
 func parseResponseBody(doer Doer, req *http.Request) (int, []byte, error) {
 	resp, err := doer.Do(req)
 	if err != nil {
@@ -0,0 +1,85 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if d, err := time.ParseDuration(r.URL.Query().Get("sleep")); err == nil {
+			select {
+			case <-time.After(d):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"traceId":"` + r.Header.Get("X-Trace-Id") + `"}`))
+	}))
+	defer server.Close()
+
+	var transportCalled bool
+	c := NewClient(
+		WithTimeout(EndpointConfig, 100*time.Millisecond),
+		WithTransport(roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			transportCalled = true
+			return http.DefaultTransport.RoundTrip(req)
+		})),
+		WithMiddleware(func(next Doer) Doer {
+			return DoerFunc(func(req *http.Request) (*http.Response, error) {
+				req.Header.Set("X-Trace-Id", "trace-1")
+				return next.Do(req)
+			})
+		}),
+	)
+
+	tests := []struct {
+		endpoint Endpoint
+		url      string
+		isErr    bool
+		expected string
+	}{
+		{EndpointConfig, server.URL, false, "trace-1"},
+		{EndpointConfig, server.URL + "?sleep=1s", true, ""},
+		{EndpointLongPoll, server.URL + "?sleep=200ms", false, "trace-1"},
+	}
+
+	for i, test := range tests {
+		t.Logf("run test (%v): %v", i, test.url)
+
+		var resp struct {
+			TraceID string `json:"traceId"`
+		}
+		status, err := c.Do(context.Background(), test.endpoint, "GET", test.url, nil, &resp)
+		if test.isErr {
+			if err == nil {
+				t.Errorf("  should timeout (status=%v)", status)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("  unexpected error: %v", err)
+		}
+		if resp.TraceID != test.expected {
+			t.Errorf("  should be equal (expected=%v, actual=%v)", test.expected, resp.TraceID)
+		}
+	}
+
+	if !transportCalled {
+		t.Errorf("custom transport should be used")
+	}
+
+	if actual := c.Timeout(EndpointMetaServer); actual != defaultMetaServerTimeout {
+		t.Errorf("should be equal (expected=%v, actual=%v)", defaultMetaServerTimeout, actual)
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -1,10 +1,14 @@
 package str
 
 import (
+	"encoding/json"
 	"fmt"
 	"html/template"
+	"math"
 	"reflect"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // From html/template/content.go
@@ -51,9 +55,9 @@ func ToStringE(i interface{}) (string, error) {
 	case int8:
 		return strconv.FormatInt(int64(s), 10), nil
 	case uint:
-		return strconv.FormatInt(int64(s), 10), nil
+		return strconv.FormatUint(uint64(s), 10), nil
 	case uint64:
-		return strconv.FormatInt(int64(s), 10), nil
+		return strconv.FormatUint(s, 10), nil
 	case uint32:
 		return strconv.FormatInt(int64(s), 10), nil
 	case uint16:
@@ -83,6 +87,329 @@ func ToStringE(i interface{}) (string, error) {
 	}
 }
 
+// indirect returns the value, after dereferencing as many times
+// as necessary to reach the base type (or nil).
+func indirect(a interface{}) interface{} {
+	if a == nil {
+		return nil
+	}
+	if t := reflect.TypeOf(a); t.Kind() != reflect.Ptr {
+		// Avoid creating a reflect.Value if it's not a pointer.
+		return a
+	}
+	v := reflect.ValueOf(a)
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	return v.Interface()
+}
+
+// ToInt64E casts an interface to an int64 type.
+// Floats with a fractional part and values out of the int64 range are errors rather than truncated.
+func ToInt64E(i interface{}) (int64, error) {
+	i = indirect(i)
+
+	switch s := i.(type) {
+	case int:
+		return int64(s), nil
+	case int64:
+		return s, nil
+	case int32:
+		return int64(s), nil
+	case int16:
+		return int64(s), nil
+	case int8:
+		return int64(s), nil
+	case uint:
+		return uintToInt64(uint64(s))
+	case uint64:
+		return uintToInt64(s)
+	case uint32:
+		return int64(s), nil
+	case uint16:
+		return int64(s), nil
+	case uint8:
+		return int64(s), nil
+	case float64:
+		return floatToInt64(s)
+	case float32:
+		return floatToInt64(float64(s))
+	case json.Number:
+		return s.Int64()
+	case string:
+		v, err := parseInt(strings.TrimSpace(s))
+		if err != nil {
+			return 0, fmt.Errorf("unable to cast %#v of type %T to int64", i, i)
+		}
+		return v, nil
+	case bool:
+		if s {
+			return 1, nil
+		}
+		return 0, nil
+	case nil:
+		return 0, nil
+	default:
+		return 0, fmt.Errorf("unable to cast %#v of type %T to int64", i, i)
+	}
+}
+
+func uintToInt64(v uint64) (int64, error) {
+	if v > math.MaxInt64 {
+		return 0, fmt.Errorf("unable to cast %d to int64: out of range", v)
+	}
+	return int64(v), nil
+}
+
+func floatToInt64(v float64) (int64, error) {
+	// float64(math.MaxInt64) rounds up to 2^63, which is already out of range.
+	if v != math.Trunc(v) || v < math.MinInt64 || v >= math.MaxInt64 {
+		return 0, fmt.Errorf("unable to cast %v to int64: not an integer or out of range", v)
+	}
+	return int64(v), nil
+}
+
+// parseInt parses s as a base 10 integer, so "010" is 10 rather than octal 8.
+// Hex is only accepted with an explicit 0x prefix.
+func parseInt(s string) (int64, error) {
+	sign, digits := "", s
+	if strings.HasPrefix(digits, "-") || strings.HasPrefix(digits, "+") {
+		sign, digits = digits[:1], digits[1:]
+	}
+	if strings.HasPrefix(digits, "0x") || strings.HasPrefix(digits, "0X") {
+		return strconv.ParseInt(sign+digits[2:], 16, 64)
+	}
+	return strconv.ParseInt(s, 10, 64)
+}
+
+// ToIntE casts an interface to an int type.
+func ToIntE(i interface{}) (int, error) {
+	v, err := ToInt64E(i)
+	if err != nil || int64(int(v)) != v {
+		return 0, fmt.Errorf("unable to cast %#v of type %T to int", i, i)
+	}
+	return int(v), nil
+}
+
+// ToFloat64E casts an interface to a float64 type.
+func ToFloat64E(i interface{}) (float64, error) {
+	i = indirect(i)
+
+	switch s := i.(type) {
+	case float64:
+		return s, nil
+	case float32:
+		return float64(s), nil
+	case int:
+		return float64(s), nil
+	case int64:
+		return float64(s), nil
+	case int32:
+		return float64(s), nil
+	case int16:
+		return float64(s), nil
+	case int8:
+		return float64(s), nil
+	case uint:
+		return float64(s), nil
+	case uint64:
+		return float64(s), nil
+	case uint32:
+		return float64(s), nil
+	case uint16:
+		return float64(s), nil
+	case uint8:
+		return float64(s), nil
+	case json.Number:
+		return s.Float64()
+	case string:
+		v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			return 0, fmt.Errorf("unable to cast %#v of type %T to float64", i, i)
+		}
+		return v, nil
+	case bool:
+		if s {
+			return 1, nil
+		}
+		return 0, nil
+	case nil:
+		return 0, nil
+	default:
+		return 0, fmt.Errorf("unable to cast %#v of type %T to float64", i, i)
+	}
+}
+
+// ToBoolE casts an interface to a bool type.
+func ToBoolE(i interface{}) (bool, error) {
+	i = indirect(i)
+
+	switch b := i.(type) {
+	case bool:
+		return b, nil
+	case nil:
+		return false, nil
+	case int, int64, int32, int16, int8, uint, uint64, uint32, uint16, uint8, float64, float32:
+		v, err := ToFloat64E(b)
+		if err != nil {
+			return false, err
+		}
+		return v != 0, nil
+	case string:
+		v, err := strconv.ParseBool(strings.TrimSpace(b))
+		if err != nil {
+			return false, fmt.Errorf("unable to cast %#v of type %T to bool", i, i)
+		}
+		return v, nil
+	default:
+		return false, fmt.Errorf("unable to cast %#v of type %T to bool", i, i)
+	}
+}
+
+// ToDurationE casts an interface to a time.Duration type.
+// Integers and strings without unit are treated as nanoseconds.
+func ToDurationE(i interface{}) (time.Duration, error) {
+	i = indirect(i)
+
+	switch s := i.(type) {
+	case time.Duration:
+		return s, nil
+	case int, int64, int32, int16, int8, uint, uint64, uint32, uint16, uint8, float64, float32, json.Number:
+		v, err := ToInt64E(s)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(v), nil
+	case string:
+		s = strings.TrimSpace(s)
+		if s == "" {
+			return 0, nil
+		}
+		if strings.ContainsAny(s, "nsuµmh") {
+			return time.ParseDuration(s)
+		}
+		return time.ParseDuration(s + "ns")
+	case nil:
+		return 0, nil
+	default:
+		return 0, fmt.Errorf("unable to cast %#v of type %T to time.Duration", i, i)
+	}
+}
+
+var timeFormats = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC822Z,
+	time.RFC822,
+	time.ANSIC,
+}
+
+// ToTimeE casts an interface to a time.Time type.
+// Integers are treated as unix timestamps in seconds.
+func ToTimeE(i interface{}) (time.Time, error) {
+	i = indirect(i)
+
+	switch s := i.(type) {
+	case time.Time:
+		return s, nil
+	case string:
+		s = strings.TrimSpace(s)
+		for _, format := range timeFormats {
+			if t, err := time.Parse(format, s); err == nil {
+				return t, nil
+			}
+		}
+		if v, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return time.Unix(v, 0), nil
+		}
+		return time.Time{}, fmt.Errorf("unable to parse date: %s", s)
+	case int, int64, int32, uint, uint64, uint32, json.Number:
+		v, err := ToInt64E(s)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Unix(v, 0), nil
+	case nil:
+		return time.Time{}, nil
+	default:
+		return time.Time{}, fmt.Errorf("unable to cast %#v of type %T to time.Time", i, i)
+	}
+}
+
+// ToStringSliceE casts an interface to a []string type.
+// Strings are split by sep, and each element is trimmed.
+func ToStringSliceE(i interface{}, sep string) ([]string, error) {
+	i = indirect(i)
+
+	switch v := i.(type) {
+	case []string:
+		return v, nil
+	case []interface{}:
+		var a []string
+		for _, u := range v {
+			s, err := ToStringE(u)
+			if err != nil {
+				return nil, fmt.Errorf("unable to cast %#v of type %T to []string", i, i)
+			}
+			a = append(a, s)
+		}
+		return a, nil
+	case string:
+		if strings.TrimSpace(v) == "" {
+			return []string{}, nil
+		}
+		a := strings.Split(v, sep)
+		for idx := range a {
+			a[idx] = strings.TrimSpace(a[idx])
+		}
+		return a, nil
+	case nil:
+		return []string{}, nil
+	default:
+		return nil, fmt.Errorf("unable to cast %#v of type %T to []string", i, i)
+	}
+}
+
+// ToStringMapE casts an interface to a map[string]interface{} type.
+// Strings are decoded as JSON objects.
+func ToStringMapE(i interface{}) (map[string]interface{}, error) {
+	i = indirect(i)
+
+	var m = map[string]interface{}{}
+
+	switch v := i.(type) {
+	case map[string]interface{}:
+		return v, nil
+	case map[interface{}]interface{}:
+		for k, val := range v {
+			m[fmt.Sprint(k)] = val
+		}
+		return m, nil
+	case map[string]string:
+		for k, val := range v {
+			m[k] = val
+		}
+		return m, nil
+	case string:
+		if strings.TrimSpace(v) == "" {
+			return m, nil
+		}
+		if err := json.Unmarshal([]byte(v), &m); err != nil {
+			return nil, fmt.Errorf("unable to cast %#v of type %T to map[string]interface{}: %v", i, i, err)
+		}
+		return m, nil
+	case nil:
+		return m, nil
+	default:
+		return nil, fmt.Errorf("unable to cast %#v of type %T to map[string]interface{}", i, i)
+	}
+}
 
 func StringInSlice(t string, ss []string) bool {
 	for _, s := range ss {
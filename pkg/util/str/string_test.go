@@ -0,0 +1,61 @@
+package str
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestToE(t *testing.T) {
+	tests := []struct {
+		input    interface{}
+		cast     func(interface{}) (interface{}, error)
+		expected interface{}
+		isErr    bool
+	}{
+		{"8", func(i interface{}) (interface{}, error) { return ToIntE(i) }, 8, false},
+		{" 0x10 ", func(i interface{}) (interface{}, error) { return ToIntE(i) }, 16, false},
+		{"010", func(i interface{}) (interface{}, error) { return ToIntE(i) }, 10, false},
+		{"08", func(i interface{}) (interface{}, error) { return ToIntE(i) }, 8, false},
+		{"-0x10", func(i interface{}) (interface{}, error) { return ToInt64E(i) }, int64(-16), false},
+		{"0b11", func(i interface{}) (interface{}, error) { return ToIntE(i) }, 0, true},
+		{"eight", func(i interface{}) (interface{}, error) { return ToIntE(i) }, 0, true},
+		{18.0, func(i interface{}) (interface{}, error) { return ToInt64E(i) }, int64(18), false},
+		{18.9, func(i interface{}) (interface{}, error) { return ToInt64E(i) }, int64(0), true},
+		{"1.9", func(i interface{}) (interface{}, error) { return ToIntE(i) }, 0, true},
+		{1e19, func(i interface{}) (interface{}, error) { return ToInt64E(i) }, int64(0), true},
+		{uint64(math.MaxUint64), func(i interface{}) (interface{}, error) { return ToInt64E(i) }, int64(0), true},
+		{uint64(math.MaxUint64), func(i interface{}) (interface{}, error) { return ToStringE(i) }, "18446744073709551615", false},
+		{"1.5", func(i interface{}) (interface{}, error) { return ToFloat64E(i) }, 1.5, false},
+		{"true", func(i interface{}) (interface{}, error) { return ToBoolE(i) }, true, false},
+		{1, func(i interface{}) (interface{}, error) { return ToBoolE(i) }, true, false},
+		{"yes", func(i interface{}) (interface{}, error) { return ToBoolE(i) }, false, true},
+		{"1m30s", func(i interface{}) (interface{}, error) { return ToDurationE(i) }, 90 * time.Second, false},
+		{"1000", func(i interface{}) (interface{}, error) { return ToDurationE(i) }, time.Microsecond, false},
+		{"2020-01-02", func(i interface{}) (interface{}, error) { return ToTimeE(i) }, time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC), false},
+		{"a, b,c", func(i interface{}) (interface{}, error) { return ToStringSliceE(i, ",") }, []string{"a", "b", "c"}, false},
+		{"a|b", func(i interface{}) (interface{}, error) { return ToStringSliceE(i, "|") }, []string{"a", "b"}, false},
+		{`{"name":"foo"}`, func(i interface{}) (interface{}, error) { return ToStringMapE(i) }, map[string]interface{}{"name": "foo"}, false},
+		{"{", func(i interface{}) (interface{}, error) { return ToStringMapE(i) }, map[string]interface{}(nil), true},
+		{nil, func(i interface{}) (interface{}, error) { return ToIntE(i) }, 0, false},
+	}
+
+	for i, test := range tests {
+		t.Logf("run test (%v): %v", i, test.input)
+
+		actual, err := test.cast(test.input)
+		if test.isErr {
+			if err == nil {
+				t.Errorf("  should return error (input=%v)", test.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("  unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("  should be equal (expected=%v, actual=%v)", test.expected, actual)
+		}
+	}
+}
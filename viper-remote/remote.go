@@ -12,8 +12,6 @@ import (
 	crypt "github.com/xordataexchange/crypt/config"
 
 	"github.com/sixgoatsh/agollo/core/agollo"
-	"github.com/sixgoatsh/agollo/core/client"
-	"github.com/sixgoatsh/agollo/core/client/balancer"
 	"github.com/sixgoatsh/agollo/core/options"
 )
 
@@ -79,20 +77,12 @@ func newApolloConfigManager(appid, endpoint string, opts []options.Option) (*apo
 func newAgollo(appID, endpoint string, opts []options.Option) (agollo.GoApollo, error) {
 	i, found := agolloMap.Load(agolloKey(appID, endpoint))
 	if !found {
-		innerOpts, err := options.NewOptions(endpoint, appID, opts...)
-		if err != nil {
-			return nil, err
-		}
-		newBalancer, err := balancer.NewBalancer(innerOpts.Conf, innerOpts.EnableSLB, innerOpts.RefreshIntervalInSecond, innerOpts.Logger, &client.MetaServerClient{})
-		if err != nil {
-			return nil, err
-		}
-
+		// 由GoApollo根据Options创建客户端及负载均衡，包括本地模式
 		ag, err := agollo.NewGoApollo(
 			endpoint,
 			appID,
-			client.New(),
-			newBalancer,
+			nil,
+			nil,
 			opts...,
 		)
 		if err != nil {
@@ -111,18 +101,16 @@ func newAgollo(appID, endpoint string, opts []options.Option) (agollo.GoApollo,
 
 func (cm apolloConfigManager) Get(namespace string) ([]byte, error) {
 	configs := cm.agollo.GetNameSpace(namespace)
-	return marshalConfigs(getConfigType(namespace), configs)
+	return cm.marshalConfigs(namespace, configs)
 }
 
-func marshalConfigs(configType string, configs map[string]interface{}) ([]byte, error) {
+// marshalConfigs GoApollo会解析json、yaml格式的namespace，这里通过GetContent获取原始内容交给viper解析
+func (cm apolloConfigManager) marshalConfigs(namespace string, configs map[string]interface{}) ([]byte, error) {
 	var bts []byte
 	var err error
-	switch configType {
+	switch getConfigType(namespace) {
 	case "json", "yml", "yaml", "xml":
-		content := configs["content"]
-		if content != nil {
-			bts = []byte(content.(string))
-		}
+		bts = []byte(cm.agollo.GetContent(namespace))
 	case "properties":
 		bts, err = marshalProperties(configs)
 	}
@@ -146,8 +134,7 @@ func (cm apolloConfigManager) Watch(namespace string, stop chan bool) <-chan *vi
 					continue
 				}
 
-				configType := getConfigType(namespace)
-				value, err := marshalConfigs(configType, r.NewValue)
+				value, err := cm.marshalConfigs(namespace, r.NewValue)
 
 				resp <- &viper.RemoteResponse{Value: value, Error: err}
 			}